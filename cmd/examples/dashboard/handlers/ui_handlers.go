@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/primadi/lokstra"
+	"github.com/primadi/lokstra_web/web_render"
 )
 
 var rootProject = "../../../"
@@ -134,7 +135,7 @@ func DashboardHandler(ctx *lokstra.Context) error {
 
 func UsersHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if this is an HTMX request (partial content)
-	if r.Header.Get("HX-Request") == "true" {
+	if web_render.HTMXFromRequest(r).IsPartial() {
 		// Return just the page content without the full layout
 		tmpl, err := template.ParseFiles("templates/pages/users.html")
 		if err != nil {
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/primadi/lokstra_web/web_render"
+)
+
+const usage = `Usage: lokstra-web <command> [flags]
+
+Commands:
+  check    statically check templates and component usage
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "check":
+		os.Exit(runCheck(os.Args[2:]))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// runCheck loads the template tree the way TemplateLoader does and reports
+// parse errors, unresolved {{template}} references and unknown <ls-*>
+// elements/attributes as file:line.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	templatesDir := fs.String("templates", "templates", "templates dir (layouts/, pages/, partials/)")
+	componentsDir := fs.String("components", "components", "web components dir, empty to skip component checks")
+	strict := fs.Bool("strict", false, "fail on warnings too")
+	_ = fs.Parse(args)
+
+	if _, err := os.Stat(*componentsDir); *componentsDir != "" && err != nil {
+		fmt.Fprintf(os.Stderr, "components dir %s not found, skipping component checks\n", *componentsDir)
+		*componentsDir = ""
+	}
+	report, err := web_render.CheckTemplates(*templatesDir, *componentsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check: %v\n", err)
+		return 2
+	}
+
+	for _, issue := range report.Issues {
+		var file string
+		if issue.Layer == "project" {
+			file = filepath.Join(*templatesDir, issue.File)
+		} else {
+			file = issue.Layer + ":" + issue.File
+		}
+		fmt.Printf("%s:%d: %s: %s\n", file, issue.Line, issue.Severity, issue.Message)
+	}
+	fmt.Printf("checked %d templates: %d error(s), %d warning(s)\n",
+		report.Templates, report.Errors(), report.Warnings())
+
+	if report.Errors() > 0 || (*strict && report.Warnings() > 0) {
+		return 1
+	}
+	return 0
+}
@@ -232,3 +232,5 @@ export class LsInput extends LitElement {
     `;
   }
 }
+
+customElements.define("ls-input", LsInput)
@@ -2,7 +2,10 @@ module github.com/primadi/lokstra_web
 
 go 1.24.4
 
-require github.com/primadi/lokstra v0.1.5
+require (
+	github.com/primadi/lokstra v0.1.5
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/grafana/regexp v0.0.0-20240518133315-a468a5bfb3bc // indirect
@@ -49,5 +52,4 @@ require (
 	golang.org/x/text v0.28.0 // indirect
 	golang.org/x/tools v0.36.0 // indirect
 	google.golang.org/protobuf v1.36.8 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
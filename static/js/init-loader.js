@@ -29,6 +29,15 @@ if (!window.__importMapInjected) {
 if (!window.htmx) {
   const htmxScript = document.createElement("script")
   htmxScript.src = "https://unpkg.com/htmx.org@2.0.6"
+  // Swap 4xx/5xx responses too, so error pages rendered by web_render show
+  // up in the target instead of being dropped
+  htmxScript.onload = () => {
+    htmx.config.responseHandling = [
+      { code: "204", swap: false },
+      { code: "[23]..", swap: true },
+      { code: "[45]..", swap: true, error: true },
+    ]
+  }
   document.head.appendChild(htmxScript)
 }
 
@@ -0,0 +1,166 @@
+package web_render
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	scriptSrcRe  = regexp.MustCompile(`(?is)<script\b[^>]*\ssrc\s*=\s*["']([^"'{}]+)["']`)
+	linkHrefRe   = regexp.MustCompile(`(?is)<link\b[^>]*\shref\s*=\s*["']([^"'{}]+)["']`)
+	headAssetsRe = regexp.MustCompile(`\.HeadAssets\b`)
+	bodyAssetsRe = regexp.MustCompile(`\.BodyAssets\b`)
+)
+
+// sourceAssets returns the literal script and stylesheet URLs in src, so
+// page assets the layout already loads are not added twice. URLs built
+// with template actions are not seen.
+func sourceAssets(src []byte) []string {
+	var urls []string
+	for _, re := range []*regexp.Regexp{scriptSrcRe, linkHrefRe} {
+		for _, m := range re.FindAllSubmatch(src, -1) {
+			urls = append(urls, string(m[1]))
+		}
+	}
+	return urls
+}
+
+// pageAssets are the page-specific Scripts, Styles and CustomCSS of a
+// render, resolved and deduplicated.
+type pageAssets struct {
+	Scripts   []string
+	Styles    []string
+	CustomCSS string
+}
+
+func (a pageAssets) empty() bool {
+	return len(a.Scripts) == 0 && len(a.Styles) == 0 && a.CustomCSS == ""
+}
+
+// newPageAssets resolves relative URLs with resolve (the loader's asset
+// func, or nil) and drops duplicates and anything in skip, keeping the
+// declared order.
+func newPageAssets(resolve func(string) string, scripts, styles []string, customCSS string, skip map[string]bool) pageAssets {
+	seen := map[string]bool{}
+	filter := func(urls []string) []string {
+		var out []string
+		for _, u := range urls {
+			if u == "" {
+				continue
+			}
+			if resolve != nil && !strings.HasPrefix(u, "/") && !strings.Contains(u, "://") {
+				u = resolve(u)
+			}
+			if seen[u] || skip[u] {
+				continue
+			}
+			seen[u] = true
+			out = append(out, u)
+		}
+		return out
+	}
+	return pageAssets{
+		Scripts:   filter(scripts),
+		Styles:    filter(styles),
+		CustomCSS: customCSS,
+	}
+}
+
+// head renders the stylesheet links and custom CSS for <head>.
+func (a pageAssets) head() string {
+	var b strings.Builder
+	for _, href := range a.Styles {
+		b.WriteString(`<link rel="stylesheet" href="` + template.HTMLEscapeString(href) + `">`)
+	}
+	if a.CustomCSS != "" {
+		b.WriteString(`<style data-ls-page-css>` + escapeStyle(a.CustomCSS) + `</style>`)
+	}
+	return b.String()
+}
+
+// body renders the script tags for the end of <body>.
+func (a pageAssets) body() string {
+	var b strings.Builder
+	for _, src := range a.Scripts {
+		b.WriteString(`<script src="` + template.HTMLEscapeString(src) + `"></script>`)
+	}
+	return b.String()
+}
+
+// escapeStyle keeps custom CSS from closing its <style> element.
+func escapeStyle(css string) string {
+	return strings.ReplaceAll(css, "</", `<\/`)
+}
+
+// inject adds the head assets before </head> when head is set and the body
+// assets before </body> when body is set, for layouts that do not place
+// them with {{.HeadAssets}} or {{.BodyAssets}}.
+func (a pageAssets) inject(html string, head, body bool) string {
+	if h := a.head(); head && h != "" {
+		if i := strings.Index(html, "</head>"); i >= 0 {
+			html = html[:i] + h + html[i:]
+		} else {
+			html = h + html
+		}
+	}
+	if s := a.body(); body && s != "" {
+		if i := strings.LastIndex(html, "</body>"); i >= 0 {
+			html = html[:i] + s + html[i:]
+		} else {
+			html += s
+		}
+	}
+	return html
+}
+
+var (
+	assetScriptOnce sync.Once
+	assetScript     string
+)
+
+// clearPageCSS drops the previous page's custom CSS, for page navigations
+// to a page without assets.
+const clearPageCSS = `<script>(function (s) { if (s) s.remove() })(document.head.querySelector("style[data-ls-page-css]"))</script>`
+
+// partial renders the assets for an htmx page navigation: a script that
+// adds the stylesheets and scripts the client has not loaded yet, in
+// order, and swaps in the page's custom CSS, or removes the previous
+// page's when there is none.
+func (a pageAssets) partial() string {
+	if a.empty() {
+		return clearPageCSS
+	}
+	assetScriptOnce.Do(func() {
+		fw := DefaultFrameworkAssetLoader()
+		if b, err := fs.ReadFile(fw.FS, path.Join(fw.JsDir, "assets.js")); err == nil {
+			assetScript = string(b)
+		}
+	})
+	payload, err := json.Marshal(map[string]any{
+		"styles":  a.Styles,
+		"scripts": a.Scripts,
+		"css":     a.CustomCSS,
+	})
+	if err != nil {
+		return ""
+	}
+	// json.Marshal escapes <, > and &, so the payload cannot end the script
+	return "<script>" + assetScript + "\nlsLoadAssets(" + string(payload) + ")</script>"
+}
+
+// HeadAssets renders the page stylesheets and custom CSS; layouts place it
+// at the end of <head>. Assets the layout itself links are skipped.
+func (p PageContent) HeadAssets() template.HTML {
+	return template.HTML(p.assets.head())
+}
+
+// BodyAssets renders the page scripts; layouts place it right before
+// </body>. Scripts the layout itself loads are skipped.
+func (p PageContent) BodyAssets() template.HTML {
+	return template.HTML(p.assets.body())
+}
@@ -0,0 +1,185 @@
+package web_render
+
+import (
+	"strings"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// SegmentTitleFunc turns the value of a dynamic route segment into its
+// breadcrumb title, e.g. a user id into the user name.
+type SegmentTitleFunc func(c *request.Context, value string) (string, error)
+
+// routeTitle is a page route known to the breadcrumb tree.
+type routeTitle struct {
+	segments []string // "users", ":id"
+	title    string
+}
+
+// Route adds the page at pattern (e.g. "/users/:id") to the breadcrumb
+// tree, replacing its title if it is already there. FileRoutes and
+// ConfigPages add their pages themselves; call Route to title pages
+// mounted by hand, or to retitle file pages.
+//
+// Once the tree has routes, page navigations (full pages and htmx swaps
+// into the content area, see MainLayoutPage.ContentTarget) get breadcrumbs
+// derived from the request path, one entry for every leading part of it
+// that is a page, from "/" down; other fragment swaps get none. Dynamic
+// segments are titled by their SegmentTitle func, or shown as is. A
+// handler sees them in PageOptions.Breadcrumbs and may append to or
+// replace them; set an empty non-nil slice for none.
+func (m *MainLayoutPage) Route(pattern, title string) *MainLayoutPage {
+	segments := pathSegments(pattern)
+	for i, r := range m.routes {
+		if equalSegments(r.segments, segments) {
+			m.routes[i].title = title
+			return m
+		}
+	}
+	m.routes = append(m.routes, routeTitle{segments: segments, title: title})
+	return m
+}
+
+// addRoute is Route that keeps an existing title.
+func (m *MainLayoutPage) addRoute(pattern, title string) {
+	segments := pathSegments(pattern)
+	for _, r := range m.routes {
+		if equalSegments(r.segments, segments) {
+			return
+		}
+	}
+	m.routes = append(m.routes, routeTitle{segments: segments, title: title})
+}
+
+// SegmentTitle registers the title lookup for the dynamic segment param,
+// e.g. "id" in /users/:id. It runs for every navigation below such a
+// segment.
+func (m *MainLayoutPage) SegmentTitle(param string, fn SegmentTitleFunc) *MainLayoutPage {
+	if m.segmentTitles == nil {
+		m.segmentTitles = map[string]SegmentTitleFunc{}
+	}
+	m.segmentTitles[param] = fn
+	return m
+}
+
+// Breadcrumbs derives the breadcrumbs of the request path of c from the
+// route tree; nil when the tree is empty or nothing matches.
+func (m *MainLayoutPage) Breadcrumbs(c *request.Context) []Breadcrumb {
+	if len(m.routes) == 0 {
+		return nil
+	}
+	parts := pathSegments(requestPath(c))
+	var crumbs []Breadcrumb
+	for n := 0; n <= len(parts); n++ {
+		r, ok := m.matchRoute(parts[:n])
+		if !ok {
+			continue
+		}
+		crumbs = append(crumbs, Breadcrumb{
+			Title: m.crumbTitle(c, r, parts[:n]),
+			URL:   "/" + strings.Join(parts[:n], "/"),
+		})
+	}
+	if len(crumbs) > 0 {
+		crumbs[len(crumbs)-1].Active = true
+	}
+	return crumbs
+}
+
+// matchRoute finds the route matching parts, preferring static segments
+// over dynamic ones from left to right.
+func (m *MainLayoutPage) matchRoute(parts []string) (routeTitle, bool) {
+	var best routeTitle
+	found := false
+	for _, r := range m.routes {
+		if len(r.segments) != len(parts) || !matchSegments(r.segments, parts) {
+			continue
+		}
+		if !found || moreStatic(r.segments, best.segments) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// crumbTitle is the title of r at parts: its own title, else the resolved
+// or raw value of a trailing dynamic segment, else the humanized segment.
+func (m *MainLayoutPage) crumbTitle(c *request.Context, r routeTitle, parts []string) string {
+	if len(parts) == 0 {
+		if r.title == "" {
+			return "Home"
+		}
+		return r.title
+	}
+	last := r.segments[len(r.segments)-1]
+	value := parts[len(parts)-1]
+	if !strings.HasPrefix(last, ":") {
+		if r.title != "" {
+			return r.title
+		}
+		return humanize(last)
+	}
+	if fn := m.segmentTitles[last[1:]]; fn != nil {
+		title, err := fn(c, value)
+		if err == nil && title != "" {
+			return title
+		}
+		if err != nil {
+			m.Loader.logger().Warnf("breadcrumb title for %s=%s: %v", last[1:], value, err)
+		}
+	}
+	if r.title != "" {
+		return r.title
+	}
+	return value
+}
+
+func pathSegments(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func equalSegments(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for i, s := range pattern {
+		if !strings.HasPrefix(s, ":") && s != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// moreStatic reports whether a has a static segment where b first has a
+// dynamic one.
+func moreStatic(a, b []string) bool {
+	for i := range a {
+		as, bs := strings.HasPrefix(a[i], ":"), strings.HasPrefix(b[i], ":")
+		if as != bs {
+			return !as
+		}
+	}
+	return false
+}
+
+// humanize turns a path segment like "user-groups" into "User groups".
+func humanize(segment string) string {
+	s := strings.NewReplacer("-", " ", "_", " ").Replace(segment)
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
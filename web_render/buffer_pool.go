@@ -0,0 +1,55 @@
+package web_render
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer keeps one huge page from pinning its buffer in the pool.
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer resets buf and returns it to the pool.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// countingWriter counts the bytes written through it, so callers know
+// whether an error page can still replace a failed render.
+type countingWriter struct {
+	w       io.Writer
+	n       int64
+	onFirst func() // runs before the first byte, e.g. to set headers
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	// An empty write would still commit the status line
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if cw.onFirst != nil {
+		cw.onFirst()
+		cw.onFirst = nil
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer, for streamed slots.
+func (cw *countingWriter) Flush() {
+	flush(cw.w)
+}
@@ -0,0 +1,155 @@
+package web_render
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ComponentRegistry maps each custom element tag (e.g. "ls-button") to the
+// HTML attributes it accepts, as declared by its Lit properties.
+type ComponentRegistry map[string]map[string]bool
+
+var (
+	defineRe     = regexp.MustCompile(`customElements\.define\(\s*["']([a-z][a-z0-9]*-[a-z0-9-]*)["']\s*,\s*(\w+)`)
+	classRe      = regexp.MustCompile(`class\s+(\w+)\s+extends\s+`)
+	propsStartRe = regexp.MustCompile(`static\s+(?:properties\s*=|get\s+properties\s*\(\s*\)\s*\{\s*return)\s*\{`)
+	propRe       = regexp.MustCompile(`(\w+)\s*:\s*\{([^{}]*)\}`)
+	attrNameRe   = regexp.MustCompile(`attribute\s*:\s*["']([^"']+)["']`)
+	attrFalseRe  = regexp.MustCompile(`attribute\s*:\s*false`)
+	stateRe      = regexp.MustCompile(`state\s*:\s*true`)
+	hostAttrRe   = regexp.MustCompile(`:host\(\[([a-zA-Z0-9-]+)`)
+)
+
+// LoadComponentRegistry scans the .js files in fsys (e.g. os.DirFS("components"))
+// for customElements.define calls and the Lit properties of the defined
+// classes. Lit's default attribute name is the lowercased property name;
+// state properties and attribute: false are skipped. Attributes only used
+// for styling, like :host([fullwidth]), are accepted too.
+func LoadComponentRegistry(fsys fs.FS) (ComponentRegistry, error) {
+	classAttrs := map[string]map[string]bool{}
+	defines := map[string]string{} // tag -> class
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".js" {
+			return err
+		}
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		js := string(src)
+		for name, attrs := range litClassAttributes(js) {
+			classAttrs[name] = attrs
+		}
+		for _, m := range defineRe.FindAllStringSubmatch(js, -1) {
+			defines[m[1]] = m[2]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reg := ComponentRegistry{}
+	for tag, class := range defines {
+		attrs := classAttrs[class]
+		if attrs == nil {
+			attrs = map[string]bool{}
+		}
+		reg[tag] = attrs
+	}
+	return reg, nil
+}
+
+// litClassAttributes returns the attributes of every class in js that
+// declares Lit properties or styles :host([attr]).
+func litClassAttributes(js string) map[string]map[string]bool {
+	result := map[string]map[string]bool{}
+	classes := classRe.FindAllStringSubmatchIndex(js, -1)
+	for i, c := range classes {
+		end := len(js)
+		if i+1 < len(classes) {
+			end = classes[i+1][0]
+		}
+		body := js[c[1]:end]
+		attrs := map[string]bool{}
+		for _, m := range hostAttrRe.FindAllStringSubmatch(body, -1) {
+			attrs[strings.ToLower(m[1])] = true
+		}
+		loc := propsStartRe.FindStringIndex(body)
+		if loc == nil {
+			if len(attrs) > 0 {
+				result[js[c[2]:c[3]]] = attrs
+			}
+			continue
+		}
+		obj := matchBraces(body[loc[1]-1:])
+		for _, m := range propRe.FindAllStringSubmatch(obj, -1) {
+			opts := m[2]
+			switch {
+			case attrFalseRe.MatchString(opts), stateRe.MatchString(opts):
+				continue
+			case attrNameRe.MatchString(opts):
+				attrs[strings.ToLower(attrNameRe.FindStringSubmatch(opts)[1])] = true
+			default:
+				attrs[strings.ToLower(m[1])] = true
+			}
+		}
+		result[js[c[2]:c[3]]] = attrs
+	}
+	return result
+}
+
+// matchBraces returns s up to the brace closing the one s starts with.
+func matchBraces(s string) string {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return s
+}
+
+// Tags returns the registered tags, sorted.
+func (r ComponentRegistry) Tags() []string {
+	tags := make([]string, 0, len(r))
+	for tag := range r {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// globalAttrPrefixes are accepted on every custom element: data/aria,
+// htmx, Alpine (x-, @, :) and inline event handlers.
+var globalAttrPrefixes = []string{"data-", "aria-", "hx-", "x-", "@", ":", "on"}
+
+var globalAttrs = map[string]bool{
+	"id": true, "class": true, "style": true, "slot": true, "hidden": true,
+	"title": true, "role": true, "tabindex": true, "lang": true, "dir": true,
+	"part": true, "is": true, "name": true,
+}
+
+// AllowsAttribute reports whether tag accepts attr, either as a declared
+// component property or as a global attribute.
+func (r ComponentRegistry) AllowsAttribute(tag, attr string) bool {
+	attr = strings.ToLower(attr)
+	if globalAttrs[attr] || r[tag][attr] {
+		return true
+	}
+	for _, prefix := range globalAttrPrefixes {
+		if strings.HasPrefix(attr, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package web_render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// DefaultErrorTemplates maps status codes to the error page templates
+// rendered by RenderError. Other statuses use "errors/error". The framework
+// provides all of them; projects override them file by file, e.g. with
+// templates/pages/errors/404.html.
+var DefaultErrorTemplates = map[int]string{
+	http.StatusForbidden:           "errors/403",
+	http.StatusNotFound:            "errors/404",
+	http.StatusInternalServerError: "errors/500",
+}
+
+// RenderError writes the error page for err with its HTTP status (see
+// ErrorStatus): the full layout for browser requests, only the error
+// template for htmx partial requests. With DevErrors on, template errors
+// show the developer page instead, with file, line, failing expression and
+// the surrounding source.
+//
+//	page, err := layout.RenderPage(c, "users", data, opts)
+//	if err != nil {
+//		return layout.RenderError(c, err)
+//	}
+//	return c.HTML(page.HTML)
+func (m *MainLayoutPage) RenderError(c *request.Context, err error) error {
+	status := ErrorStatus(err)
+	log := m.Loader.logger()
+	if status >= 500 {
+		log.Errorf("render error %d: %v", status, err)
+	} else {
+		log.Debugf("render error %d: %v", status, err)
+	}
+
+	var te *TemplateError
+	if m.DevErrors && errors.As(err, &te) {
+		return writeHTML(c, status, m.devErrorPage(status, te))
+	}
+
+	data := ErrorData{Status: status, StatusText: http.StatusText(status)}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		data.Message = he.Message
+	}
+	name := m.ErrorTemplates[status]
+	if name == "" {
+		name = DefaultErrorTemplates[status]
+	}
+	if name == "" {
+		name = "errors/error"
+	}
+	page, renderErr := m.RenderPage(c, name, data, &PageOptions{
+		Title: strconv.Itoa(status) + " " + data.StatusText,
+		SEO:   SEO{Robots: "noindex"},
+	})
+	if renderErr != nil {
+		// The error page itself is broken; fall back to plain text
+		log.Errorf("render error page %s: %v", name, renderErr)
+		return writeHTML(c, status, "<h1>"+strconv.Itoa(status)+" "+template.HTMLEscapeString(data.StatusText)+"</h1>")
+	}
+	if err := page.Response.Apply(c.Writer.Header()); err != nil {
+		return err
+	}
+	return writeHTML(c, status, page.HTML)
+}
+
+// writeHTML writes html with an explicit status code.
+func writeHTML(c *request.Context, status int, html string) error {
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Writer.WriteHeader(status)
+	_, err := io.WriteString(c.Writer, html)
+	return err
+}
+
+var (
+	devErrorOnce sync.Once
+	devErrorTmpl *template.Template
+	devErrorErr  error
+)
+
+// sourceLine is one line of the excerpt on the developer error page.
+type sourceLine struct {
+	N       int
+	Text    string
+	Current bool
+}
+
+// devErrorPage renders the developer error page for te. It is parsed
+// straight from the embedded framework files, so it works even when the
+// project layouts or partials are what is broken.
+func (m *MainLayoutPage) devErrorPage(status int, te *TemplateError) string {
+	devErrorOnce.Do(func() {
+		devErrorTmpl, devErrorErr = template.ParseFS(frameworkFS, "framework/dev/error.html")
+	})
+	if devErrorErr != nil {
+		return "<pre>" + template.HTMLEscapeString(te.Error()) + "</pre>"
+	}
+
+	if te.Path == "" {
+		m.Loader.locate(te)
+	}
+	kind := "Template error"
+	if te.Kind != nil {
+		kind = te.Kind.Error()
+	}
+	var buf strings.Builder
+	err := devErrorTmpl.Execute(&buf, map[string]any{
+		"Status": status,
+		"Kind":   kind,
+		"Err":    te,
+		"Source": m.Loader.sourceExcerpt(te, 4),
+	})
+	if err != nil {
+		return "<pre>" + template.HTMLEscapeString(te.Error()) + "</pre>"
+	}
+	return buf.String()
+}
+
+// locate fills in the layer and path of the file te.Name came from.
+func (l *TemplateLoader) locate(te *TemplateError) {
+	if strings.HasPrefix(te.Name, "partials/") {
+		for _, src := range l.partials(nil) {
+			if src.Name == te.Name {
+				te.Layer, te.Path = src.Layer, src.Path
+				return
+			}
+		}
+		return
+	}
+	if src, err := l.find(te.Name, nil); err == nil {
+		te.Layer, te.Path = src.Layer, src.Path
+	}
+}
+
+// sourceExcerpt returns the lines around te.Line of the file te points at.
+func (l *TemplateLoader) sourceExcerpt(te *TemplateError, context int) []sourceLine {
+	if te.Path == "" || te.Line == 0 {
+		return nil
+	}
+	for _, layer := range l.allLayers() {
+		if layer.Name != te.Layer || layer.FS == nil {
+			continue
+		}
+		content, err := fs.ReadFile(layer.FS, te.Path)
+		if err != nil {
+			return nil
+		}
+		lines := strings.Split(string(content), "\n")
+		var out []sourceLine
+		for n := max(1, te.Line-context); n <= min(len(lines), te.Line+context); n++ {
+			out = append(out, sourceLine{N: n, Text: lines[n-1], Current: n == te.Line})
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package web_render
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Built-in fragment ids. The framework base layout places all three, so
+// their out-of-band swaps always find a target.
+const (
+	FragmentBreadcrumbs  = "ls-breadcrumbs"   // sets the <ls-navbar> breadcrumb
+	FragmentSidebarState = "ls-sidebar-state" // sets the <ls-sidebar> active item
+	FragmentFlash        = "ls-flash"         // flash messages, appended on htmx swaps
+)
+
+// Fragment is a named piece of a page that lives outside #pageContent,
+// e.g. an alert area or a counter in the navbar. On full loads a layout
+// places it with {{.Fragment "id"}}; in htmx partial responses it is sent
+// after the page content as an hx-swap-oob element, so one response
+// updates every part of the screen the page touches.
+type Fragment struct {
+	ID       string        // element id, the target of the out-of-band swap
+	Tag      string        // wrapper element, default "div"
+	Swap     string        // hx-swap-oob value, default "true" (replace by id)
+	Template string        // template executed with Data, e.g. "partials/cart-count"
+	Data     any           // data for Template
+	Provider string        // layout provider whose result is Data; see LayoutProvider
+	HTML     template.HTML // rendered content; set directly instead of Template
+}
+
+// Breadcrumb is one item of the navbar breadcrumb.
+type Breadcrumb struct {
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Active bool   `json:"active,omitempty"`
+}
+
+// FlashMessage is a one-off notice rendered as an <ls-alert>.
+type FlashMessage struct {
+	Variant string // info, success, warning or error; default info
+	Title   string
+	Message string
+}
+
+// wrap renders the fragment element, marked for an out-of-band swap when oob.
+func (f Fragment) wrap(oob bool) string {
+	tag := f.Tag
+	if tag == "" {
+		tag = "div"
+	}
+	var b strings.Builder
+	b.WriteString("<" + tag + ` id="` + template.HTMLEscapeString(f.ID) + `"`)
+	if oob {
+		swap := f.Swap
+		if swap == "" {
+			swap = "true"
+		}
+		b.WriteString(` hx-swap-oob="` + template.HTMLEscapeString(swap) + `"`)
+	}
+	b.WriteString(">")
+	b.WriteString(string(f.HTML))
+	b.WriteString("</" + tag + ">")
+	return b.String()
+}
+
+// Fragment renders fragment id in place, for layouts:
+//
+//	<div class="content-area">{{.Fragment "ls-flash"}}<div id="pageContent">...
+//
+// An id the page did not set renders as an empty element, so later
+// out-of-band swaps still have a target.
+func (p PageContent) Fragment(id string) template.HTML {
+	for _, f := range p.Fragments {
+		if f.ID == id {
+			return template.HTML(f.wrap(false))
+		}
+	}
+	return template.HTML(Fragment{ID: id}.wrap(false))
+}
+
+// oobFragments renders every fragment for an htmx partial response.
+func (p *PageContent) oobFragments() string {
+	var b strings.Builder
+	for _, f := range p.Fragments {
+		b.WriteString(f.wrap(true))
+	}
+	return b.String()
+}
+
+// pageFragments collects the built-in fragments for the set fields of
+// opts, followed by opts.Fragments.
+func pageFragments(opts *PageOptions) []Fragment {
+	if opts == nil {
+		return nil
+	}
+	var frags []Fragment
+	if len(opts.Breadcrumbs) > 0 {
+		frags = append(frags, Fragment{ID: FragmentBreadcrumbs, Template: "partials/lokstra/breadcrumbs", Data: opts.Breadcrumbs})
+	}
+	if opts.CurrentPage != "" {
+		frags = append(frags, Fragment{ID: FragmentSidebarState, Template: "partials/lokstra/sidebar-state", Data: opts.CurrentPage})
+	}
+	if len(opts.Flash) > 0 {
+		frags = append(frags, Fragment{ID: FragmentFlash, Swap: "beforeend", Template: "partials/lokstra/flash", Data: opts.Flash})
+	}
+	return append(frags, opts.Fragments...)
+}
+
+// renderFragments executes the Template of each fragment that has no HTML
+// yet, using tmpl (which holds every partial). Failures are logged and
+// leave the fragment empty rather than breaking the page.
+func renderFragments(tmpl *template.Template, frags []Fragment, log Logger, tr *RenderTrace) []Fragment {
+	for i, f := range frags {
+		if f.HTML != "" || f.Template == "" {
+			continue
+		}
+		if tmpl == nil {
+			log.Warnf("fragment %s: no template set to render %s", f.ID, f.Template)
+			continue
+		}
+		var buf strings.Builder
+		if err := executeTraced(tmpl, &buf, f.Template, f.Data, tr); err != nil {
+			log.Errorf("fragment %s: %v", f.ID, err)
+			continue
+		}
+		frags[i].HTML = template.HTML(buf.String())
+	}
+	return frags
+}
@@ -0,0 +1,48 @@
+package web_render
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// frameworkFS holds the built-in layouts: base.html, sidebar.html and
+// error pages under errors/, plus the built-in fragment partials under
+// partials/lokstra/ and the scripts and stylesheets under static/.
+// Projects override the templates file by file.
+//
+//go:embed framework
+var frameworkFS embed.FS
+
+// DefaultFrameworkAssetPath is where the framework scripts and stylesheets
+// are served from; the framework base layout links them there.
+const DefaultFrameworkAssetPath = "/__lokstra/static/"
+
+// DefaultFrameworkAssetLoader returns the loader for the built-in
+// framework assets embedded in this package.
+func DefaultFrameworkAssetLoader() *FrameworkAssetLoader {
+	return NewFrameworkAssetLoaderWithEmbed("framework", &frameworkFS)
+}
+
+// ServeHTTP serves the framework scripts from JsDir and stylesheets from
+// CssDir, as DefaultFrameworkAssetPath + "js/..." and "css/...". Mount it
+// when pages render in the framework base layout:
+//
+//	app.RawHandle(web_render.DefaultFrameworkAssetPath, loader.Framework)
+func (f *FrameworkAssetLoader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kind, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, DefaultFrameworkAssetPath), "/")
+	var dir string
+	switch kind {
+	case "js":
+		dir = f.JsDir
+	case "css":
+		dir = f.CssDir
+	}
+	if dir == "" || !fs.ValidPath(name) || name == "." {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFileFS(w, r, f.FS, path.Join(dir, name))
+}
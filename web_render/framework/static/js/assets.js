@@ -0,0 +1,38 @@
+// Loads page assets delivered with an htmx partial response: stylesheets
+// and scripts not on the page yet are added in order (scripts one after
+// another), and the page custom CSS replaces the previous page's, or
+// removes it when the page has none.
+window.lsLoadAssets =
+  window.lsLoadAssets ||
+  function (assets) {
+    var head = document.head
+    ;(assets.styles || []).forEach(function (href) {
+      if (document.querySelector('link[href="' + CSS.escape(href) + '"]')) return
+      var link = document.createElement("link")
+      link.rel = "stylesheet"
+      link.href = href
+      head.appendChild(link)
+    })
+    var style = head.querySelector("style[data-ls-page-css]")
+    if (!assets.css) {
+      if (style) style.remove()
+    } else {
+      if (!style) {
+        style = document.createElement("style")
+        style.setAttribute("data-ls-page-css", "")
+        head.appendChild(style)
+      }
+      style.textContent = assets.css
+    }
+    var scripts = (assets.scripts || []).filter(function (src) {
+      return !document.querySelector('script[src="' + CSS.escape(src) + '"]')
+    })
+    ;(function next() {
+      var src = scripts.shift()
+      if (!src) return
+      var script = document.createElement("script")
+      script.src = src
+      script.onload = script.onerror = next
+      head.appendChild(script)
+    })()
+  }
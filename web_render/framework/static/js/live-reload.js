@@ -0,0 +1,33 @@
+// Lokstra dev live reload client.
+// Injected by web_render.HotReloader into full-page renders; never used in production.
+;(function () {
+  if (window.__lokstraLiveReload) return
+  window.__lokstraLiveReload = true
+
+  const endpoint = "{{ENDPOINT}}"
+  const source = new EventSource(endpoint)
+
+  // Template, component or script changes: reload the whole page
+  source.addEventListener("reload", () => {
+    console.log("[lokstra] change detected, reloading page")
+    window.location.reload()
+  })
+
+  // CSS-only changes: hot-swap stylesheets without losing page state
+  source.addEventListener("css", (e) => {
+    const files = JSON.parse(e.data).files || []
+    console.log("[lokstra] stylesheet changed:", files)
+    const stamp = Date.now()
+    document.querySelectorAll('link[rel="stylesheet"]').forEach((link) => {
+      const url = new URL(link.href, window.location.href)
+      if (url.origin !== window.location.origin) return
+      url.searchParams.set("_lr", stamp)
+      link.href = url.toString()
+    })
+  })
+
+  source.onerror = () => {
+    // Server restarting; EventSource reconnects on its own
+    console.log("[lokstra] live reload disconnected, retrying...")
+  }
+})()
@@ -0,0 +1,130 @@
+// Runtime of the framework base layout: loads htmx and defines plain
+// versions of the ls-sidebar, ls-navbar and ls-alert elements the built-in
+// templates use, for apps without the Lokstra component library.
+
+if (!window.htmx) {
+  var htmxScript = document.createElement("script")
+  htmxScript.src = "https://unpkg.com/htmx.org@2.0.6"
+  // Swap 4xx/5xx responses too, so error pages show up in the target
+  htmxScript.onload = function () {
+    htmx.config.responseHandling = [
+      { code: "204", swap: false },
+      { code: "[23]..", swap: true },
+      { code: "[45]..", swap: true, error: true },
+    ]
+  }
+  document.head.appendChild(htmxScript)
+}
+
+function lsDefine(name, ctor) {
+  if (!customElements.get(name)) customElements.define(name, ctor)
+}
+
+function lsMenuLink(item) {
+  var a = document.createElement("a")
+  a.textContent = item.title
+  a.href = item.url || "#"
+  a.dataset.key = item.key
+  if (item.hxGet) {
+    a.setAttribute("hx-get", item.hxGet)
+    a.setAttribute("hx-target", item.hxTarget || "#pageContent")
+    a.setAttribute("hx-push-url", item.url || "true")
+  }
+  if (item.badge) a.append(" (" + item.badge + ")")
+  return a
+}
+
+lsDefine(
+  "ls-sidebar",
+  class extends HTMLElement {
+    static get observedAttributes() {
+      return ["menuitems", "activeitem"]
+    }
+    get activeItem() {
+      return this.getAttribute("activeItem") || ""
+    }
+    set activeItem(key) {
+      this.setAttribute("activeItem", key)
+    }
+    attributeChangedCallback() {
+      this.render()
+    }
+    connectedCallback() {
+      this.render()
+    }
+    render() {
+      var groups = []
+      try {
+        groups = JSON.parse(this.getAttribute("menuItems") || "[]")
+      } catch (e) {}
+      var active = this.activeItem
+      var nav = document.createElement("nav")
+      var list = function (items) {
+        var ul = document.createElement("ul")
+        ;(items || []).forEach(function (item) {
+          var li = document.createElement("li")
+          var a = lsMenuLink(item)
+          if (item.key === active) a.classList.add("active")
+          li.appendChild(a)
+          if (item.submenu) li.appendChild(list(item.submenu))
+          ul.appendChild(li)
+        })
+        return ul
+      }
+      groups.forEach(function (group) {
+        if (group.title) {
+          var title = document.createElement("div")
+          title.className = "ls-menu-group"
+          title.textContent = group.title
+          nav.appendChild(title)
+        }
+        nav.appendChild(list(group.items))
+      })
+      this.replaceChildren(nav)
+      if (window.htmx) htmx.process(this)
+    }
+  }
+)
+
+lsDefine(
+  "ls-navbar",
+  class extends HTMLElement {
+    set breadcrumb(crumbs) {
+      var nav = document.createElement("nav")
+      nav.setAttribute("aria-label", "Breadcrumb")
+      ;(crumbs || []).forEach(function (crumb, i) {
+        if (i > 0) nav.append(" / ")
+        var el = document.createElement(crumb.url && !crumb.active ? "a" : "span")
+        el.textContent = crumb.title
+        if (el.tagName === "A") el.href = crumb.url
+        if (crumb.active) el.setAttribute("aria-current", "page")
+        nav.appendChild(el)
+      })
+      this.replaceChildren(nav)
+    }
+  }
+)
+
+lsDefine(
+  "ls-alert",
+  class extends HTMLElement {
+    connectedCallback() {
+      var self = this
+      self.setAttribute("role", "alert")
+      var text = document.createElement("span")
+      var title = self.getAttribute("title")
+      text.textContent = (title ? title + ": " : "") + (self.getAttribute("message") || "")
+      self.replaceChildren(text)
+      if (self.hasAttribute("dismissible")) {
+        var close = document.createElement("button")
+        close.type = "button"
+        close.setAttribute("aria-label", "Dismiss")
+        close.textContent = "×"
+        close.onclick = function () {
+          self.remove()
+        }
+        self.prepend(close)
+      }
+    }
+  }
+)
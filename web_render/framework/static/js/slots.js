@@ -0,0 +1,14 @@
+// Moves a streamed deferred slot (<template id="ls-slot-ID">) into its
+// placeholder element (id="ID"), replacing the placeholder's children.
+window.lsSwapSlot =
+  window.lsSwapSlot ||
+  function (id) {
+    var tpl = document.getElementById("ls-slot-" + id)
+    var target = document.getElementById(id)
+    if (tpl && target) {
+      target.replaceChildren(tpl.content.cloneNode(true))
+      target.removeAttribute("aria-busy")
+      if (window.htmx) window.htmx.process(target)
+    }
+    if (tpl) tpl.remove()
+  }
@@ -0,0 +1,47 @@
+package web_render
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestFrameworkAssets checks that the framework layouts link only assets
+// the framework serves.
+func TestFrameworkAssets(t *testing.T) {
+	fw := DefaultFrameworkAssetLoader()
+	layouts, err := fs.Glob(fw.FS, fw.LayoutDir+"/*.html")
+	if err != nil || len(layouts) == 0 {
+		t.Fatalf("no framework layouts: %v", err)
+	}
+	for _, p := range layouts {
+		src, err := fs.ReadFile(fw.FS, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range sourceAssets(src) {
+			if !strings.HasPrefix(u, "/") || strings.HasPrefix(u, "//") {
+				continue
+			}
+			if !strings.HasPrefix(u, DefaultFrameworkAssetPath) {
+				t.Errorf("%s links %s, outside %s", p, u, DefaultFrameworkAssetPath)
+				continue
+			}
+			w := httptest.NewRecorder()
+			fw.ServeHTTP(w, httptest.NewRequest(http.MethodGet, u, nil))
+			if w.Code != http.StatusOK || w.Body.Len() == 0 {
+				t.Errorf("%s links %s: status %d", p, u, w.Code)
+			}
+		}
+	}
+
+	for _, u := range []string{"js/", "js/../layouts/base.html", "templates/base.html", "css/missing.css"} {
+		w := httptest.NewRecorder()
+		fw.ServeHTTP(w, httptest.NewRequest(http.MethodGet, DefaultFrameworkAssetPath+u, nil))
+		if w.Code == http.StatusOK {
+			t.Errorf("%s: status 200, want an error", u)
+		}
+	}
+}
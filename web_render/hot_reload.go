@@ -0,0 +1,236 @@
+package web_render
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHotReloadPath is where HotReloader is usually mounted.
+const DefaultHotReloadPath = "/__lokstra/reload"
+
+// HotReloader is a development helper that polls template, component and
+// static directories for changes. On a change it invalidates the
+// TemplateLoader cache and notifies connected browsers over Server-Sent
+// Events: "css" when only stylesheets changed (hot-swapped in place),
+// "reload" for everything else.
+//
+// Usage (development only):
+//
+//	reloader := layout.EnableHotReload(rootProject+"components", rootProject+"static")
+//	app.RawHandle(web_render.DefaultHotReloadPath, reloader)
+type HotReloader struct {
+	Path     string        // SSE endpoint the injected script subscribes to
+	Interval time.Duration // polling interval, default 500ms
+
+	loader  *TemplateLoader
+	dirs    []watchDir
+	script  string
+	clients map[chan reloadEvent]struct{}
+	mu      sync.Mutex
+	stop    chan struct{}
+	files   map[string]fileStamp
+}
+
+type watchDir struct {
+	name string // prefix for reported paths, e.g. "static"
+	fsys fs.FS
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type reloadEvent struct {
+	kind  string // "reload" or "css"
+	files []string
+}
+
+// NewHotReloader watches every layer of loader plus extraDirs on disk
+// (typically components/ and static/). The embedded framework layer is
+// not watched.
+func NewHotReloader(loader *TemplateLoader, extraDirs ...string) *HotReloader {
+	h := &HotReloader{
+		Path:     DefaultHotReloadPath,
+		Interval: 500 * time.Millisecond,
+		loader:   loader,
+		clients:  map[chan reloadEvent]struct{}{},
+	}
+	for _, layer := range loader.Layers {
+		if layer.FS != nil {
+			h.dirs = append(h.dirs, watchDir{name: layer.Name, fsys: layer.FS})
+		}
+	}
+	for _, dir := range extraDirs {
+		h.dirs = append(h.dirs, watchDir{name: filepath.Base(dir), fsys: os.DirFS(dir)})
+	}
+	if fw := DefaultFrameworkAssetLoader(); fw.FS != nil {
+		if b, err := fs.ReadFile(fw.FS, path.Join(fw.JsDir, "live-reload.js")); err == nil {
+			h.script = string(b)
+		}
+	}
+	return h
+}
+
+// Start begins polling in a background goroutine. The first scan only
+// records the current state.
+func (h *HotReloader) Start() {
+	h.mu.Lock()
+	if h.stop != nil {
+		h.mu.Unlock()
+		return
+	}
+	h.stop = make(chan struct{})
+	stop := h.stop
+	h.mu.Unlock()
+
+	h.files = h.scan()
+	interval := h.Interval
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				h.poll()
+			}
+		}
+	}()
+}
+
+// Stop ends polling. Connected browsers stay subscribed until they disconnect.
+func (h *HotReloader) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+}
+
+// poll rescans all directories and broadcasts an event when files changed.
+func (h *HotReloader) poll() {
+	current := h.scan()
+	var changed []string
+	for p, stamp := range current {
+		if old, ok := h.files[p]; !ok || old != stamp {
+			changed = append(changed, p)
+		}
+	}
+	for p := range h.files {
+		if _, ok := current[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	h.files = current
+	if len(changed) == 0 {
+		return
+	}
+
+	h.loader.Invalidate()
+	ev := reloadEvent{kind: "css", files: changed}
+	for _, p := range changed {
+		if path.Ext(p) != ".css" {
+			ev.kind = "reload"
+			break
+		}
+	}
+	h.loader.logger().Infof("hot reload: %d file(s) changed, sending %q", len(changed), ev.kind)
+	h.broadcast(ev)
+}
+
+// scan walks every watched directory and stamps each file.
+func (h *HotReloader) scan() map[string]fileStamp {
+	files := map[string]fileStamp{}
+	for _, d := range h.dirs {
+		_ = fs.WalkDir(d.fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			files[path.Join(d.name, p)] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return files
+}
+
+func (h *HotReloader) broadcast(ev reloadEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- ev:
+		default: // client is slow; it will catch the next event
+		}
+	}
+}
+
+// ServeHTTP is the Server-Sent Events endpoint browsers subscribe to.
+func (h *HotReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan reloadEvent, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+	}()
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-ch:
+			data, _ := json.Marshal(map[string]any{"files": ev.files})
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.kind, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// Script returns the <script> tag that subscribes a page to this reloader.
+func (h *HotReloader) Script() template.HTML {
+	js := strings.ReplaceAll(h.script, "{{ENDPOINT}}", template.JSEscapeString(h.Path))
+	return template.HTML("<script>" + js + "</script>")
+}
+
+// inject adds the live reload script before </body>, or at the end.
+func (h *HotReloader) inject(html string) string {
+	script := string(h.Script())
+	if i := strings.LastIndex(html, "</body>"); i >= 0 {
+		return html[:i] + script + html[i:]
+	}
+	return html + script
+}
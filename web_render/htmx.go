@@ -0,0 +1,56 @@
+package web_render
+
+import (
+	"net/http"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// HTMXRequest holds the htmx request headers of one request.
+// See https://htmx.org/reference/#request_headers
+type HTMXRequest struct {
+	Request        bool   // HX-Request: request was made by htmx
+	Boosted        bool   // HX-Boosted: navigation via hx-boost
+	HistoryRestore bool   // HX-History-Restore-Request: history cache miss
+	CurrentURL     string // HX-Current-URL: browser URL when the request was made
+	Target         string // HX-Target: id of the target element
+	Trigger        string // HX-Trigger: id of the triggering element
+	TriggerName    string // HX-Trigger-Name: name of the triggering element
+	Prompt         string // HX-Prompt: user response to hx-prompt
+}
+
+// HTMX parses the htmx headers of c. A nil context yields a non-htmx request.
+func HTMX(c *request.Context) HTMXRequest {
+	if c == nil {
+		return HTMXRequest{}
+	}
+	return parseHTMX(c.GetHeader)
+}
+
+// HTMXFromRequest is HTMX for raw http.Handler code.
+func HTMXFromRequest(r *http.Request) HTMXRequest {
+	if r == nil {
+		return HTMXRequest{}
+	}
+	return parseHTMX(r.Header.Get)
+}
+
+func parseHTMX(get func(string) string) HTMXRequest {
+	return HTMXRequest{
+		Request:        get("HX-Request") == "true",
+		Boosted:        get("HX-Boosted") == "true",
+		HistoryRestore: get("HX-History-Restore-Request") == "true",
+		CurrentURL:     get("HX-Current-URL"),
+		Target:         get("HX-Target"),
+		Trigger:        get("HX-Trigger"),
+		TriggerName:    get("HX-Trigger-Name"),
+		Prompt:         get("HX-Prompt"),
+	}
+}
+
+// IsPartial reports whether the response should be a bare fragment.
+// Boosted navigation and history restores swap the whole body, so they
+// need the full page like a normal browser request.
+func (h HTMXRequest) IsPartial() bool {
+	return h.Request && !h.Boosted && !h.HistoryRestore
+}
@@ -0,0 +1,199 @@
+package web_render
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HTMXResponse holds htmx response directives sent as HX-* headers.
+// See https://htmx.org/reference/#response_headers
+//
+//	pc.HX().
+//		Trigger("userCreated", map[string]any{"id": user.ID}).
+//		Trigger("closeModal", nil).
+//		PushURL("/users")
+type HTMXResponse struct {
+	Triggers            map[string]any // HX-Trigger: event name -> payload (nil for none)
+	TriggersAfterSettle map[string]any // HX-Trigger-After-Settle
+	TriggersAfterSwap   map[string]any // HX-Trigger-After-Swap
+	PushURLValue        string         // HX-Push-Url, "false" prevents a history entry
+	ReplaceURLValue     string         // HX-Replace-Url
+	RedirectURL         string         // HX-Redirect: client-side full redirect
+	RefreshPage         bool           // HX-Refresh: full page refresh
+	RetargetSelector    string         // HX-Retarget: CSS selector for the new target
+	ReswapValue         string         // HX-Reswap: hx-swap value, e.g. "outerHTML"
+	LocationValue       *HTMXLocation  // HX-Location: client-side navigation without reload
+}
+
+// HTMXLocation is the HX-Location payload.
+type HTMXLocation struct {
+	Path    string            `json:"path"`
+	Target  string            `json:"target,omitempty"`
+	Swap    string            `json:"swap,omitempty"`
+	Source  string            `json:"source,omitempty"`
+	Event   string            `json:"event,omitempty"`
+	Select  string            `json:"select,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Values  map[string]any    `json:"values,omitempty"`
+}
+
+func addTrigger(m *map[string]any, event string, payload any) {
+	if *m == nil {
+		*m = map[string]any{}
+	}
+	(*m)[event] = payload
+}
+
+// Trigger fires event on the client as soon as the response is received.
+func (r *HTMXResponse) Trigger(event string, payload any) *HTMXResponse {
+	addTrigger(&r.Triggers, event, payload)
+	return r
+}
+
+// TriggerAfterSettle fires event after the settle step.
+func (r *HTMXResponse) TriggerAfterSettle(event string, payload any) *HTMXResponse {
+	addTrigger(&r.TriggersAfterSettle, event, payload)
+	return r
+}
+
+// TriggerAfterSwap fires event after the swap step.
+func (r *HTMXResponse) TriggerAfterSwap(event string, payload any) *HTMXResponse {
+	addTrigger(&r.TriggersAfterSwap, event, payload)
+	return r
+}
+
+// PushURL pushes url onto the browser history.
+func (r *HTMXResponse) PushURL(url string) *HTMXResponse {
+	r.PushURLValue = url
+	return r
+}
+
+// ReplaceURL replaces the current URL in the location bar.
+func (r *HTMXResponse) ReplaceURL(url string) *HTMXResponse {
+	r.ReplaceURLValue = url
+	return r
+}
+
+// Redirect makes the client do a full redirect to url.
+func (r *HTMXResponse) Redirect(url string) *HTMXResponse {
+	r.RedirectURL = url
+	return r
+}
+
+// Refresh makes the client do a full page refresh.
+func (r *HTMXResponse) Refresh() *HTMXResponse {
+	r.RefreshPage = true
+	return r
+}
+
+// Retarget swaps the response into selector instead of the request target.
+func (r *HTMXResponse) Retarget(selector string) *HTMXResponse {
+	r.RetargetSelector = selector
+	return r
+}
+
+// Reswap overrides the swap strategy, e.g. "outerHTML" or "none".
+func (r *HTMXResponse) Reswap(swap string) *HTMXResponse {
+	r.ReswapValue = swap
+	return r
+}
+
+// Location navigates the client without a full reload.
+func (r *HTMXResponse) Location(loc HTMXLocation) *HTMXResponse {
+	r.LocationValue = &loc
+	return r
+}
+
+// clone copies r with its own trigger maps and location; payloads are
+// shared.
+func (r *HTMXResponse) clone() *HTMXResponse {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Triggers = maps.Clone(r.Triggers)
+	c.TriggersAfterSettle = maps.Clone(r.TriggersAfterSettle)
+	c.TriggersAfterSwap = maps.Clone(r.TriggersAfterSwap)
+	if r.LocationValue != nil {
+		loc := *r.LocationValue
+		loc.Headers = maps.Clone(loc.Headers)
+		loc.Values = maps.Clone(loc.Values)
+		c.LocationValue = &loc
+	}
+	return &c
+}
+
+// Apply writes the directives into h.
+func (r *HTMXResponse) Apply(h http.Header) error {
+	if r == nil {
+		return nil
+	}
+	for header, events := range map[string]map[string]any{
+		"HX-Trigger":              r.Triggers,
+		"HX-Trigger-After-Settle": r.TriggersAfterSettle,
+		"HX-Trigger-After-Swap":   r.TriggersAfterSwap,
+	} {
+		v, err := encodeTriggers(events)
+		if err != nil {
+			return err
+		}
+		if v != "" {
+			h.Set(header, v)
+		}
+	}
+	setIf := func(k, v string) {
+		if v != "" {
+			h.Set(k, v)
+		}
+	}
+	setIf("HX-Push-Url", r.PushURLValue)
+	setIf("HX-Replace-Url", r.ReplaceURLValue)
+	setIf("HX-Redirect", r.RedirectURL)
+	setIf("HX-Retarget", r.RetargetSelector)
+	setIf("HX-Reswap", r.ReswapValue)
+	if r.RefreshPage {
+		h.Set("HX-Refresh", "true")
+	}
+	if r.LocationValue != nil {
+		if r.LocationValue.Target == "" && r.LocationValue.Swap == "" && r.LocationValue.Source == "" &&
+			r.LocationValue.Event == "" && r.LocationValue.Select == "" &&
+			len(r.LocationValue.Headers) == 0 && len(r.LocationValue.Values) == 0 {
+			h.Set("HX-Location", r.LocationValue.Path)
+		} else {
+			b, err := json.Marshal(r.LocationValue)
+			if err != nil {
+				return err
+			}
+			h.Set("HX-Location", string(b))
+		}
+	}
+	return nil
+}
+
+// encodeTriggers uses the plain "a, b" form when no event has a payload,
+// and the JSON object form otherwise.
+func encodeTriggers(events map[string]any) (string, error) {
+	if len(events) == 0 {
+		return "", nil
+	}
+	names := make([]string, 0, len(events))
+	plain := true
+	for name, payload := range events {
+		names = append(names, name)
+		if payload != nil {
+			plain = false
+		}
+	}
+	if plain {
+		sort.Strings(names)
+		return strings.Join(names, ", "), nil
+	}
+	b, err := json.Marshal(events)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -1,8 +1,14 @@
 package web_render
 
 import (
+	"context"
 	"html/template"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/primadi/lokstra/core/request"
 )
@@ -14,16 +20,99 @@ type PageOptions struct {
 	Scripts     []string
 	Styles      []string
 	CustomCSS   string
-	MetaTags    map[string]string
+	MetaTags    map[string]string // extra <meta name=... content=...> tags
 	SidebarData any
+	Params      map[string]string // route path params, exposed to layouts as .Params
+	SEO                           // description, canonical, robots, OpenGraph, Twitter, JSON-LD
+
+	Layout     string // layout to use instead of the page's own and MainLayoutPage.Name
+	Partial    bool   // render the page without layouts, as for an htmx swap
+	Navigation bool   // treat a partial as a page navigation, see MainLayoutPage.ContentTarget
+
+	Breadcrumbs []Breadcrumb   // navbar breadcrumb, default derived from the route tree (see Route); navigations only
+	Flash       []FlashMessage // one-off notices for the ls-flash area
+	Fragments   []Fragment     // extra out-of-band fragments
+	Slots       []Slot         // deferred sections streamed after the page
+
+	Response *HTMXResponse // htmx response directives, applied by WritePage; see HX
+}
+
+// HX returns the htmx response directives sent with the page, creating
+// them on first use.
+func (o *PageOptions) HX() *HTMXResponse {
+	if o.Response == nil {
+		o.Response = &HTMXResponse{}
+	}
+	return o.Response
+}
+
+// clone copies the containers of o a handler can append to or change for
+// one request: slices, maps and the SEO and htmx structs. What is held as
+// any (SidebarData, JSONLD entries, fragment data, trigger payloads) is
+// shared as it is, so treat it as read-only or replace it in the copy.
+func (o *PageOptions) clone() *PageOptions {
+	c := *o
+	c.Scripts = slices.Clone(o.Scripts)
+	c.Styles = slices.Clone(o.Styles)
+	c.MetaTags = maps.Clone(o.MetaTags)
+	c.Params = maps.Clone(o.Params)
+	c.SEO = o.SEO.clone()
+	c.Breadcrumbs = slices.Clone(o.Breadcrumbs)
+	c.Flash = slices.Clone(o.Flash)
+	c.Fragments = slices.Clone(o.Fragments)
+	c.Slots = slices.Clone(o.Slots)
+	c.Response = o.Response.clone()
+	return &c
 }
 
 var mainLayoutPage = "base.html"
 
+// DefaultContentTarget is the id of the page content area in the
+// framework layouts.
+const DefaultContentTarget = "pageContent"
+
+// LayoutData is what every layout level executes with. A nested layout
+// receives the rendered page (or inner layout) as Content.
+type LayoutData struct {
+	PageContent
+	Content template.HTML // rendered inner level
+	Data    any           // page data passed to RenderPage, shared by all levels
+}
+
 // MainLayoutPage struct untuk menyimpan nama layout utama
 type MainLayoutPage struct {
 	Name   string
 	Loader *TemplateLoader
+
+	// HotReload, when set, injects the live reload script into full pages.
+	HotReload *HotReloader
+
+	// TitleFormat formats the document title, e.g. "{{.Title}} - Lokstra
+	// Framework"; see DefaultTitleFormat. Layouts use {{.DocTitle}}, and
+	// htmx partial responses carry it in a <title> htmx applies.
+	TitleFormat string
+
+	// ContentTarget is the id of the element page navigations swap into,
+	// default DefaultContentTarget. Only htmx partials targeting it (or
+	// with PageOptions.Navigation) load the page assets; other fragment
+	// swaps leave the page around them alone.
+	ContentTarget string
+
+	// ErrorTemplates overrides DefaultErrorTemplates per status code.
+	ErrorTemplates map[int]string
+	// DevErrors makes RenderError show template errors with file, line and
+	// source. Development only; EnableHotReload turns it on.
+	DevErrors bool
+
+	// Menu is the sidebar navigation; see Menu.
+	Menu Menu
+	// Authorize reports whether the request holds all of permissions. It
+	// filters Menu, and guards config pages without their own Authorize.
+	Authorize func(c *request.Context, permissions []string) bool
+
+	providers     []LayoutProvider            // see Provide
+	routes        []routeTitle                // breadcrumb tree, see Route
+	segmentTitles map[string]SegmentTitleFunc // see SegmentTitle
 }
 
 // NewMainLayoutPage: inisialisasi layout utama
@@ -48,107 +137,370 @@ func (m *MainLayoutPage) Get() string {
 	return m.Name
 }
 
+// Precompile parses the main layout with every page up front.
+// Call it at startup in production to fail fast on template errors.
+func (m *MainLayoutPage) Precompile() error {
+	return m.Loader.Precompile(m.Name)
+}
+
+// EnableHotReload switches the loader to ModeDevelopment, turns on
+// DevErrors and starts a HotReloader watching the loader layers plus extraDirs. Mount the returned
+// reloader at its Path. Development only.
+func (m *MainLayoutPage) EnableHotReload(extraDirs ...string) *HotReloader {
+	m.Loader.SetMode(ModeDevelopment)
+	m.DevErrors = true
+	m.HotReload = NewHotReloader(m.Loader, extraDirs...)
+	m.HotReload.Start()
+	return m.HotReload
+}
+
 // RenderPage: API utama untuk render halaman dengan layout dan data
 // Menggunakan TemplateLoader override/fallback
+//
+// Failures come back as *TemplateError (see ErrTemplateNotFound,
+// ErrTemplateParse, ErrTemplateExecute); pass them to RenderError for the
+// error page with the right status code. opts may be nil.
 func (m *MainLayoutPage) RenderPage(
 	c *request.Context,
 	templateName string,
 	data any,
 	opts *PageOptions,
-) *PageContent {
+) (*PageContent, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	pc, err := m.RenderPageTo(buf, c, templateName, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	pc.HTML = buf.String()
+	return pc, nil
+}
+
+// RenderPageTo is RenderPage executing straight into w: the page and inner
+// layout levels go through pooled buffers and only the outermost layout of
+// a full page streams into w. htmx partials reach w only once the page has
+// rendered. The returned PageContent has no HTML. On error part of a full
+// page may already be written; see WritePage.
+func (m *MainLayoutPage) RenderPageTo(
+	w io.Writer,
+	c *request.Context,
+	templateName string,
+	data any,
+	opts *PageOptions,
+) (*PageContent, error) {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	// Breadcrumbs belong to navigations; a fragment swap leaves the
+	// navbar alone
+	nav := m.isNavigation(c, opts)
+	crumbs := nav && opts.Breadcrumbs == nil && len(m.routes) > 0
+	if (opts.CurrentPage == "" && len(m.Menu) > 0) || crumbs || (!nav && opts.Breadcrumbs != nil) {
+		o := *opts
+		if o.CurrentPage == "" {
+			o.CurrentPage = m.Menu.Active(requestPath(c))
+		}
+		if crumbs {
+			o.Breadcrumbs = m.Breadcrumbs(c)
+		} else if !nav {
+			o.Breadcrumbs = nil
+		}
+		opts = &o
+	}
 	// use loader from struct
 	loader := m.Loader
-	contentHTML := ""
+	log := loader.logger()
+
+	// Fragment only for plain htmx swaps; boosted and history-restore
+	// requests get the full page
+	fullLayout := !HTMX(c).IsPartial() && !opts.Partial
+
+	// Layout: opts.Layout wins, then the layout the page declares itself,
+	// then m.Name
+	layoutName := m.Name
+	forceLayout := false
+	if opts.Layout != "" {
+		layoutName = opts.Layout
+		forceLayout = true
+	}
+
+	var trace *RenderTrace
+	if loader.Trace {
+		trace = &RenderTrace{Template: templateName}
+		defer m.finishTrace(c, trace, time.Now())
+	}
+
+	// Layout providers load while the templates do
+	frags := pageFragments(opts)
+	ctx, cancel := context.WithCancel(requestContext(c))
+	defer cancel()
+	providers := m.startProviders(ctx, c, fullLayout, frags)
+
 	// When fullLayout, load all templates needed for composition
 	var tmpl *template.Template
-	var err error
+	var set *cachedTemplate
+	if fullLayout {
+		// Page, its layout chain, and sidebar partial parsed together (cached
+		// per layout+page). Anything the project does not provide falls back
+		// to framework layouts.
+		var err error
+		if set, err = loader.loadPage(layoutName, templateName, forceLayout, trace); err != nil {
+			return nil, err
+		}
+		tmpl = set.tmpl
+		if trace != nil && len(set.layouts) > 0 {
+			trace.Layout = strings.Join(set.layouts, " > ")
+		}
+	} else {
+		// Only load the page template for partial/HTMX
+		var err error
+		if tmpl, err = loader.load(templateName+".html", trace); err != nil {
+			return nil, err
+		}
+	}
 
-	isHTMX := false
-	if c != nil {
-		isHTMX = c.GetHeader("HX-Request") == "true"
+	// Deferred slots start loading now and stream after the page
+	var slots <-chan slotResult
+	if len(opts.Slots) > 0 {
+		slots = startSlots(ctx, opts.Slots)
 	}
-	fullLayout := true
-	if isHTMX {
-		fullLayout = false
+
+	pc := &PageContent{
+		Title:       opts.Title,
+		CurrentPage: opts.CurrentPage,
+		MetaTags:    opts.MetaTags,
+		SidebarData: opts.SidebarData,
+		Params:      opts.Params,
+		SEO:         opts.SEO,
+		Breadcrumbs: opts.Breadcrumbs,
+		Flash:       opts.Flash,
+		Scripts:     opts.Scripts,
+		Styles:      opts.Styles,
+		CustomCSS:   opts.CustomCSS,
+		Trace:       trace,
+		Response:    opts.Response,
+		composed:    true,
+	}
+	pc.Provided = providers.wait(log, trace)
+	if pc.SidebarData == nil {
+		pc.SidebarData = pc.Provided[ProviderSidebar]
 	}
-	if opts != nil && opts.MetaTags != nil {
-		if v, ok := opts.MetaTags["full_layout"]; ok && v == "false" {
-			fullLayout = false
+	for i, f := range frags {
+		if f.Provider != "" {
+			frags[i].Data = pc.Provided[f.Provider]
 		}
 	}
+	pc.DocTitle = formatTitle(m.TitleFormat, pc, log)
+	pc.Fragments = renderFragments(tmpl, frags, log, trace)
+
+	// Slots of a full page go before </body>, the rest of it after them
+	var tail *bodyTailWriter
+	if fullLayout && slots != nil {
+		tail = &bodyTailWriter{w: w}
+	}
 
 	if fullLayout {
-		// Load layout, page, and sidebar partial/component
-		layoutPath := loader.LayoutDir + "/" + m.Name
-		pagePath := loader.PageDir + "/" + templateName + ".html"
-		sidebarPath := loader.LayoutDir + "/sidebar.html"
-		// Add more partials/components as needed
-		tmpl, err = template.ParseFiles(layoutPath, pagePath, sidebarPath)
-	} else {
-		// Only load the page template for partial/HTMX
-		pagePath := loader.PageDir + "/" + templateName + ".html"
-		tmpl, err = template.ParseFiles(pagePath)
-	}
-	if err == nil && tmpl != nil {
-		var buf strings.Builder
-		if fullLayout {
-			if err := tmpl.ExecuteTemplate(&buf, m.Name, data); err == nil {
-				contentHTML = buf.String()
-			} else {
-				contentHTML = "<div>Layout execution error: " + err.Error() + "</div>"
-			}
-		} else {
-			if err := tmpl.ExecuteTemplate(&buf, templateName+".html", data); err == nil {
-				contentHTML = buf.String()
-			} else {
-				contentHTML = "<div>Template execution error: " + err.Error() + "</div>"
-			}
+		pc.Menu = m.menuFor(c)
+		pc.assets = newPageAssets(loader.assetURL, opts.Scripts, opts.Styles, opts.CustomCSS, set.assets)
+		var dst io.Writer = w
+		if tail != nil {
+			dst = tail
+		}
+		if err := m.executeLayouts(dst, set, templateName, data, pc, trace); err != nil {
+			return nil, err
 		}
 	} else {
-		contentHTML = "<div>Template not found: " + templateName + ".html</div>"
+		// Page buffered, so a failing page still gets the error page. Only
+		// then its title and the page assets the client may not have yet go
+		// out, ahead of it; fragments ride along as out-of-band swaps
+		page := getBuffer()
+		defer putBuffer(page)
+		if err := executeTraced(tmpl, page, templateName+".html", data, trace); err != nil {
+			return nil, err
+		}
+		pc.assets = newPageAssets(loader.assetURL, opts.Scripts, opts.Styles, opts.CustomCSS, nil)
+		head := titleTag(pc.DocTitle)
+		if nav {
+			head += pc.assets.partial()
+		}
+		if _, err := io.WriteString(w, head); err != nil {
+			return nil, err
+		}
+		if _, err := page.WriteTo(w); err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, pc.oobFragments()); err != nil {
+			return nil, err
+		}
+	}
+	if slots != nil {
+		if err := m.streamSlots(w, tmpl, slots, len(opts.Slots), !fullLayout, trace); err != nil {
+			return nil, err
+		}
 	}
+	if tail != nil {
+		if err := tail.writeTail(); err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
 
-	html := contentHTML
-	if fullLayout {
-		// Bisa override via opts.MetaTags["main_layout"]
-		layoutName := m.Name
-		if opts != nil && opts.MetaTags != nil {
-			if v, ok := opts.MetaTags["main_layout"]; ok && v != "" {
-				layoutName = v
+// isNavigation reports whether the response replaces the page rather than
+// a fragment of it: a full page, an htmx swap into ContentTarget, or a
+// partial rendered with opts.Navigation.
+func (m *MainLayoutPage) isNavigation(c *request.Context, opts *PageOptions) bool {
+	hx := HTMX(c)
+	if !hx.IsPartial() && !opts.Partial {
+		return true
+	}
+	target := m.ContentTarget
+	if target == "" {
+		target = DefaultContentTarget
+	}
+	return opts.Navigation || hx.Target == target
+}
+
+// executeLayouts renders the page inside its layout chain. Each layout
+// level gets the inner level as {{.Content}} plus the shared page data as
+// {{.Data}}, innermost first. Inner levels render into two pooled buffers
+// used in turn; the outermost goes to w, unless page assets or the live
+// reload script have to be injected into it.
+func (m *MainLayoutPage) executeLayouts(w io.Writer, set *cachedTemplate, templateName string, data any, pc *PageContent, trace *RenderTrace) error {
+	inner, outer := getBuffer(), getBuffer()
+	defer putBuffer(inner)
+	defer putBuffer(outer)
+	if err := executeTraced(set.tmpl, inner, templateName+".html", data, trace); err != nil {
+		return err
+	}
+	injectHead := !set.headHook && pc.assets.head() != ""
+	injectBody := !set.bodyHook && len(pc.assets.Scripts) > 0
+	buffered := injectHead || injectBody || m.HotReload != nil
+	layoutData := LayoutData{PageContent: *pc, Data: data}
+	for i, name := range set.layouts {
+		layoutData.Content = template.HTML(inner.String())
+		outer.Reset()
+		var dst io.Writer = outer
+		if i == len(set.layouts)-1 && !buffered {
+			dst = w
+		}
+		if err := executeTraced(set.tmpl, dst, name, layoutData, trace); err != nil {
+			return err
+		}
+		inner, outer = outer, inner
+	}
+	if len(set.layouts) > 0 && !buffered {
+		return nil
+	}
+	html := inner.String()
+	if injectHead || injectBody {
+		html = pc.assets.inject(html, injectHead, injectBody)
+	}
+	if m.HotReload != nil {
+		html = m.HotReload.inject(html)
+	}
+	_, err := io.WriteString(w, html)
+	return err
+}
+
+// requestContext is the context slot loaders run under: the request's,
+// so they stop when the client goes away.
+func requestContext(c *request.Context) context.Context {
+	if c != nil && c.Context != nil {
+		return c.Context
+	}
+	return context.Background()
+}
+
+// WritePage renders the page straight into the response of c. When
+// rendering fails before the first byte is written, the error page is
+// sent instead (see RenderError); a failure halfway through a streamed
+// page is logged and returned, since the status line is already out.
+// opts.Response goes out with the first byte, so the error page never
+// carries the page's htmx directives.
+func (m *MainLayoutPage) WritePage(c *request.Context, templateName string, data any, opts *PageOptions) error {
+	hx := http.Header{}
+	if opts != nil {
+		if err := opts.Response.Apply(hx); err != nil {
+			return m.RenderError(c, err)
+		}
+	}
+	cw := &countingWriter{w: c.Writer, onFirst: func() {
+		for k, v := range hx {
+			c.Writer.Header()[k] = v
+		}
+	}}
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := m.RenderPageTo(cw, c, templateName, data, opts); err != nil {
+		if cw.n == 0 {
+			return m.RenderError(c, err)
+		}
+		m.Loader.logger().Errorf("render %s failed after %d bytes: %v", templateName, cw.n, err)
+		return err
+	}
+	return nil
+}
+
+// PageDataFunc loads the data a page template executes with. opts is this
+// request's copy of the handler's options, to set the title, breadcrumbs,
+// flash messages or htmx directives from the loaded data. Return an
+// HTTPError (e.g. NotFound) to get the matching error page.
+type PageDataFunc func(c *request.Context, opts *PageOptions) (any, error)
+
+// Handler returns the handler rendering templateName with the data from
+// data (nil for a static page) and opts (may be nil). Full pages and htmx
+// partials take the same path, WritePage, and errors end up on the error
+// page. Register it by name to use it from YAML routes:
+//
+//	regCtx.RegisterHandler("page.users", layout.Handler("users", loadUsers,
+//		&web_render.PageOptions{Title: "Users", CurrentPage: "users"}))
+func (m *MainLayoutPage) Handler(templateName string, data PageDataFunc, opts *PageOptions) request.HandlerFunc {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	return func(c *request.Context) error {
+		o := opts.clone()
+		derived := o.Breadcrumbs == nil && m.isNavigation(c, o)
+		if derived {
+			o.Breadcrumbs = m.Breadcrumbs(c)
+		}
+		var v any
+		if data != nil {
+			var err error
+			if v, err = data(c, o); err != nil {
+				return m.RenderError(c, err)
 			}
 		}
-		// Render mainLayout and inject contentHTML into {{.Content}}
-		layoutData := struct {
-			PageContent
-			Content string
-		}{
-			PageContent: PageContent{
-				Title:       opts.Title,
-				CurrentPage: opts.CurrentPage,
-				MetaTags:    opts.MetaTags,
-				SidebarData: opts.SidebarData,
-			},
-			Content: contentHTML,
-		}
-		layoutTmpl, err := loader.Load(layoutName)
-		if err == nil && layoutTmpl != nil {
-			var buf strings.Builder
-			if err := layoutTmpl.ExecuteTemplate(&buf, layoutName, layoutData); err == nil {
-				html = buf.String()
-			} else {
-				html = "<div>Layout execution error: " + err.Error() + "</div>"
+		if derived {
+			// entries the data func appended: the last one is the page
+			for i := range o.Breadcrumbs {
+				o.Breadcrumbs[i].Active = i == len(o.Breadcrumbs)-1
 			}
-		} else {
-			html = "<div>Layout template not found: " + layoutName + "</div>" + contentHTML
 		}
+		return m.WritePage(c, templateName, v, o)
 	}
+}
 
-	// Build PageContent
-	return &PageContent{
-		HTML:        html,
-		Title:       opts.Title,
-		CurrentPage: opts.CurrentPage,
-		MetaTags:    opts.MetaTags,
-		SidebarData: opts.SidebarData,
+// finishTrace logs tr and attaches it to the request, on success and failure.
+func (m *MainLayoutPage) finishTrace(c *request.Context, tr *RenderTrace, start time.Time) {
+	tr.Total = time.Since(start)
+	m.Loader.logger().Debugf("%s", tr)
+	if c != nil && c.Request != nil {
+		c.Request = c.Request.WithContext(WithRenderTrace(c.Request.Context(), tr))
+	}
+}
+
+// executeTraced executes the named template, recording its timing in tr.
+func executeTraced(tmpl *template.Template, w io.Writer, name string, data any, tr *RenderTrace) error {
+	start := time.Now()
+	err := tmpl.ExecuteTemplate(w, name, data)
+	step := TraceStep{Kind: "execute", Name: name, Duration: time.Since(start)}
+	if err != nil {
+		step.Err = err.Error()
+	}
+	tr.add(step)
+	if err != nil {
+		return newTemplateError(ErrTemplateExecute, name, err)
 	}
+	return nil
 }
@@ -0,0 +1,36 @@
+package web_render
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// NoLayout, declared by a page, renders it without any layout even on
+// full-page requests.
+const NoLayout = "none"
+
+var (
+	// {{define "layout"}}admin.html{{end}}
+	layoutDefineRe = regexp.MustCompile(`\{\{-?\s*define\s+"layout"\s*-?\}\}\s*([^\s{}]+)\s*\{\{-?\s*end\s*-?\}\}`)
+	// {{/* layout: admin.html */}} as the first thing in the file
+	layoutCommentRe = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*layout:\s*([^\s*]+)\s*\*/\s*-?\}\}`)
+)
+
+// declaredLayout extracts the layout a template declares for itself, either
+// with a {{define "layout"}}name{{end}} block or a leading
+// {{/* layout: name */}} comment. The declaration is stripped from the
+// returned source so sets combining several templates don't collide on it;
+// line breaks are kept so error line numbers still match the file.
+func declaredLayout(src []byte) (string, []byte) {
+	m := layoutCommentRe.FindSubmatchIndex(src)
+	if m == nil {
+		m = layoutDefineRe.FindSubmatchIndex(src)
+	}
+	if m == nil {
+		return "", src
+	}
+	stripped := bytes.Clone(src[:m[0]])
+	stripped = append(stripped, bytes.Repeat([]byte("\n"), bytes.Count(src[m[0]:m[1]], []byte("\n")))...)
+	stripped = append(stripped, src[m[1]:]...)
+	return string(src[m[2]:m[3]]), stripped
+}
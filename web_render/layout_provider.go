@@ -0,0 +1,133 @@
+package web_render
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// DefaultProviderTimeout bounds a LayoutProvider without its own Timeout.
+const DefaultProviderTimeout = 2 * time.Second
+
+// ProviderSidebar is the provider whose result becomes SidebarData when
+// the handler does not set it.
+const ProviderSidebar = "sidebar"
+
+// LayoutProvider loads one piece of data every layout needs, like the
+// current user, the unread notification count or the tenant branding, so
+// handlers don't have to:
+//
+//	layout.Provide(web_render.LayoutProvider{
+//		Name: "notifications",
+//		Load: func(ctx context.Context, c *request.Context) (any, error) {
+//			user := currentUser(c) // read c up front
+//			return notifications.Unread(ctx, user)
+//		},
+//	})
+//
+// Layouts read the results from .Provided, e.g. {{.Provided.notifications}}.
+// All providers run concurrently for every full page, while the templates
+// load; one that fails or times out is logged and left out. htmx partials
+// skip them, except those a fragment names in Fragment.Provider.
+//
+// The page does not wait for a provider past its Timeout, but Go cannot
+// stop it: Load must return once ctx is done, and must not touch c after
+// that, since the handler may have returned and c been reused. Read what
+// it needs from c (user, tenant, headers) before any slow call, and pass
+// ctx to that call.
+type LayoutProvider struct {
+	Name string
+	// Load runs in its own goroutine; it must respect ctx, see above.
+	Load    func(ctx context.Context, c *request.Context) (any, error)
+	Timeout time.Duration // default DefaultProviderTimeout
+}
+
+// Provide registers p, replacing a provider with the same name. Register
+// providers at startup, before the layout serves requests.
+func (m *MainLayoutPage) Provide(p LayoutProvider) *MainLayoutPage {
+	m.providers = slices.DeleteFunc(m.providers, func(q LayoutProvider) bool { return q.Name == p.Name })
+	m.providers = append(m.providers, p)
+	return m
+}
+
+// pendingProviders is a set of providers running for one request.
+type pendingProviders struct {
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	values  map[string]any
+	steps   []TraceStep
+	started bool
+}
+
+// startProviders runs the providers of m for a full page, or the ones
+// frags name for a partial.
+func (m *MainLayoutPage) startProviders(ctx context.Context, c *request.Context, full bool, frags []Fragment) *pendingProviders {
+	pp := &pendingProviders{}
+	for _, p := range m.providers {
+		if !full && !slices.ContainsFunc(frags, func(f Fragment) bool { return f.Provider == p.Name }) {
+			continue
+		}
+		if p.Load == nil {
+			continue
+		}
+		pp.started = true
+		pp.wg.Add(1)
+		go func(p LayoutProvider) {
+			defer pp.wg.Done()
+			timeout := p.Timeout
+			if timeout <= 0 {
+				timeout = DefaultProviderTimeout
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			type result struct {
+				v   any
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				v, err := p.Load(ctx, c)
+				done <- result{v, err}
+			}()
+			var r result
+			select {
+			case r = <-done:
+			case <-ctx.Done():
+				r.err = ctx.Err()
+			}
+			step := TraceStep{Kind: "provider", Name: p.Name, Duration: time.Since(start)}
+			pp.mu.Lock()
+			defer pp.mu.Unlock()
+			if r.err != nil {
+				step.Err = r.err.Error()
+			} else {
+				if pp.values == nil {
+					pp.values = map[string]any{}
+				}
+				pp.values[p.Name] = r.v
+			}
+			pp.steps = append(pp.steps, step)
+		}(p)
+	}
+	return pp
+}
+
+// wait blocks until every provider is done or timed out, and returns
+// their results. Failures are logged and recorded in tr.
+func (pp *pendingProviders) wait(log Logger, tr *RenderTrace) map[string]any {
+	if !pp.started {
+		return nil
+	}
+	pp.wg.Wait()
+	for _, s := range pp.steps {
+		if s.Err != "" {
+			log.Warnf("layout provider %s: %s", s.Name, s.Err)
+		}
+		tr.add(s)
+	}
+	return pp.values
+}
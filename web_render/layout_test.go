@@ -0,0 +1,172 @@
+package web_render
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// newTestContext is a request context for target, with the given headers.
+func newTestContext(target string, headers map[string]string) (*request.Context, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	return &request.Context{Context: context.Background(), Writer: w, Request: r}, w
+}
+
+// testLayout is a one-level layout around the given pages.
+func testLayout(pages map[string]string) *MainLayoutPage {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<!DOCTYPE html><html><head><title>{{.DocTitle}}</title></head>` +
+			`<body><main id="pageContent">{{.Content}}</main>{{.Fragment "ls-breadcrumbs"}}</body></html>`)},
+	}
+	for name, src := range pages {
+		fsys["pages/"+name+".html"] = &fstest.MapFile{Data: []byte(src)}
+	}
+	loader := NewTemplateLoaderFS(TemplateLayer{Name: "test", FS: fsys})
+	loader.SetMode(ModeProduction)
+	return NewMainLayoutPageWithLoader("base.html", loader)
+}
+
+func TestHandler(t *testing.T) {
+	type sidebar struct {
+		mu   *sync.Mutex
+		Name string
+	}
+	shared := &sidebar{mu: &sync.Mutex{}, Name: "side"}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		opts    *PageOptions
+		want    []string
+		notWant []string
+	}{
+		{name: "full page, nil options", want: []string{"<!DOCTYPE html>", "<p>hello</p>"}},
+		{name: "full page", opts: &PageOptions{Title: "Hi", SidebarData: shared}, want: []string{"<title>Hi", "<p>hello</p>"}},
+		{
+			name:    "htmx request",
+			headers: map[string]string{"HX-Request": "true"},
+			opts:    &PageOptions{Title: "Hi"},
+			want:    []string{"<p>hello</p>"},
+			notWant: []string{"<!DOCTYPE html>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testLayout(map[string]string{"hello": `<p>hello</p>`})
+			var seen []any
+			h := m.Handler("hello", func(c *request.Context, opts *PageOptions) (any, error) {
+				seen = append(seen, opts.SidebarData)
+				opts.Scripts = append(opts.Scripts, "/x.js")
+				opts.HX().Trigger("loaded", nil)
+				return nil, nil
+			}, tt.opts)
+			for i := 0; i < 2; i++ {
+				c, w := newTestContext("/hello", tt.headers)
+				if err := h(c); err != nil {
+					t.Fatal(err)
+				}
+				if w.Code != http.StatusOK {
+					t.Fatalf("status %d, body %s", w.Code, w.Body)
+				}
+				body := w.Body.String()
+				for _, s := range tt.want {
+					if !strings.Contains(body, s) {
+						t.Errorf("body lacks %q:\n%s", s, body)
+					}
+				}
+				for _, s := range tt.notWant {
+					if strings.Contains(body, s) {
+						t.Errorf("body has %q:\n%s", s, body)
+					}
+				}
+				if got := w.Header().Get("HX-Trigger"); !strings.Contains(got, "loaded") {
+					t.Errorf("HX-Trigger = %q", got)
+				}
+			}
+			if tt.opts != nil {
+				if len(tt.opts.Scripts) != 0 || tt.opts.Response != nil {
+					t.Errorf("handler options changed: %+v", tt.opts)
+				}
+				for _, v := range seen {
+					if v != tt.opts.SidebarData {
+						t.Errorf("SidebarData was copied, want it shared")
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPartialAssets(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		opts   PageOptions
+		want   string // "" for no asset script
+	}{
+		{name: "navigation without assets", target: "pageContent", want: clearPageCSS},
+		{name: "navigation with assets", target: "pageContent", opts: PageOptions{CustomCSS: "p{}"}, want: "lsLoadAssets("},
+		{name: "fragment swap", target: "usersTable"},
+		{name: "fragment swap with assets", target: "usersTable", opts: PageOptions{CustomCSS: "p{}"}},
+		{name: "explicit navigation", target: "usersTable", opts: PageOptions{Navigation: true}, want: clearPageCSS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testLayout(map[string]string{"hello": `<p>hello</p>`})
+			c, w := newTestContext("/hello", map[string]string{"HX-Request": "true", "HX-Target": tt.target})
+			if err := m.WritePage(c, "hello", nil, &tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			body := w.Body.String()
+			if tt.want == "" {
+				if strings.Contains(body, "<script>") {
+					t.Errorf("fragment swap got an asset script:\n%s", body)
+				}
+			} else if !strings.Contains(body, tt.want) {
+				t.Errorf("body lacks %q:\n%s", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestBreadcrumbsNavigationOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "full page", want: true},
+		{name: "boosted", headers: map[string]string{"HX-Request": "true", "HX-Boosted": "true"}, want: true},
+		{name: "content swap", headers: map[string]string{"HX-Request": "true", "HX-Target": "pageContent"}, want: true},
+		{name: "fragment swap", headers: map[string]string{"HX-Request": "true", "HX-Target": "usersTable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testLayout(map[string]string{"users": `<p>users</p>`})
+			m.Route("/users", "Users").Route("/users/:id", "")
+			lookups := 0
+			m.SegmentTitle("id", func(c *request.Context, value string) (string, error) {
+				lookups++
+				return "User " + value, nil
+			})
+			c, w := newTestContext("/users/search", tt.headers)
+			if err := m.Handler("users", nil, nil)(c); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Contains(w.Body.String(), "User search")
+			if got != tt.want || (lookups > 0) != tt.want {
+				t.Errorf("breadcrumbs sent = %v, lookups = %d, want %v:\n%s", got, lookups, tt.want, w.Body)
+			}
+		})
+	}
+}
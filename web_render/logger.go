@@ -0,0 +1,47 @@
+package web_render
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Logger is the logging interface used by TemplateLoader and RenderPage.
+// lokstra.Logger satisfies it; use NewSlogLogger to adapt a *slog.Logger.
+type Logger interface {
+	Debugf(format string, v ...any)
+	Infof(format string, v ...any)
+	Warnf(format string, v ...any)
+	Errorf(format string, v ...any)
+}
+
+// nopLogger discards everything; used when no Logger is configured.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any) {}
+func (nopLogger) Infof(string, ...any)  {}
+func (nopLogger) Warnf(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+
+// slogLogger adapts *slog.Logger to Logger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger wraps l so it can be used as a web_render Logger.
+// The level and format (text/json) are whatever l's handler is configured with.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) logf(level slog.Level, format string, v []any) {
+	if !s.l.Enabled(context.Background(), level) {
+		return
+	}
+	s.l.Log(context.Background(), level, fmt.Sprintf(format, v...), "component", "web_render")
+}
+
+func (s slogLogger) Debugf(format string, v ...any) { s.logf(slog.LevelDebug, format, v) }
+func (s slogLogger) Infof(format string, v ...any)  { s.logf(slog.LevelInfo, format, v) }
+func (s slogLogger) Warnf(format string, v ...any)  { s.logf(slog.LevelWarn, format, v) }
+func (s slogLogger) Errorf(format string, v ...any) { s.logf(slog.LevelError, format, v) }
@@ -0,0 +1,143 @@
+package web_render
+
+import (
+	"strings"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// Menu is the sidebar navigation, a list of groups in the shape
+// <ls-sidebar> takes as menuItems:
+//
+//	layout.Menu = web_render.Menu{
+//		{Items: []web_render.MenuItem{
+//			{Key: "dashboard", Title: "Dashboard", URL: "/", Icon: "home"},
+//			{Key: "users", Title: "Users", URL: "/users", Icon: "users",
+//				Permissions: []string{"users.read"}},
+//		}},
+//		{Title: "Admin", Items: []web_render.MenuItem{
+//			{Key: "settings", Title: "Settings", URL: "/settings", Icon: "settings", Badge: "new"},
+//		}},
+//	}
+//
+// The framework sidebar layout renders it filtered by
+// MainLayoutPage.Authorize (items with Permissions stay hidden while it is
+// nil), with the active item matched from the request path unless the
+// page sets CurrentPage.
+type Menu []MenuGroup
+
+// MenuGroup is a titled section of the menu; Title may be empty.
+type MenuGroup struct {
+	Title string     `json:"title,omitempty"`
+	Items []MenuItem `json:"items"`
+}
+
+// MenuItem is one sidebar entry, optionally with a submenu.
+type MenuItem struct {
+	Key      string `json:"key"` // matched against CurrentPage
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+	Icon     string `json:"icon,omitempty"` // ls-icon name
+	Badge    string `json:"badge,omitempty"`
+	HXGet    string `json:"hxGet,omitempty"`
+	HXTarget string `json:"hxTarget,omitempty"`
+
+	Children []MenuItem `json:"submenu,omitempty"`
+
+	// Permissions are all required to see the item; see MainLayoutPage.Authorize.
+	Permissions []string `json:"-"`
+	// Match is the path prefix that makes the item active, default URL. A
+	// prefix matches whole segments only; "/" matches the root path alone.
+	Match string `json:"-"`
+}
+
+// Filter returns the items allow accepts the permissions of. Groups left
+// empty are dropped, and so are items that lose all their children and
+// have no URL of their own. A nil allow accepts no permissions, so only
+// items without Permissions remain, as with pages that cannot be guarded.
+func (m Menu) Filter(allow func(permissions []string) bool) Menu {
+	if allow == nil {
+		allow = func([]string) bool { return false }
+	}
+	var out Menu
+	for _, g := range m {
+		if items := filterItems(g.Items, allow); len(items) > 0 {
+			g.Items = items
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func filterItems(items []MenuItem, allow func([]string) bool) []MenuItem {
+	var out []MenuItem
+	for _, it := range items {
+		if len(it.Permissions) > 0 && !allow(it.Permissions) {
+			continue
+		}
+		if len(it.Children) > 0 {
+			it.Children = filterItems(it.Children, allow)
+			if len(it.Children) == 0 && it.URL == "" {
+				continue
+			}
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// Active returns the key of the item whose Match (or URL) is the longest
+// prefix of urlPath, children included, or "" when none matches.
+func (m Menu) Active(urlPath string) string {
+	key, best := "", -1
+	var walk func([]MenuItem)
+	walk = func(items []MenuItem) {
+		for _, it := range items {
+			prefix := it.Match
+			if prefix == "" {
+				prefix = it.URL
+			}
+			if prefix != "" && len(prefix) > best && pathHasPrefix(urlPath, prefix) {
+				key, best = it.Key, len(prefix)
+			}
+			walk(it.Children)
+		}
+	}
+	for _, g := range m {
+		walk(g.Items)
+	}
+	return key
+}
+
+// pathHasPrefix reports whether prefix is urlPath or a leading run of its
+// segments.
+func pathHasPrefix(urlPath, prefix string) bool {
+	if i := strings.IndexAny(prefix, "?#"); i >= 0 {
+		prefix = prefix[:i]
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return urlPath == "/" || urlPath == ""
+	}
+	return urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+}
+
+// menuFor is m.Menu as the request may see it. Without Authorize, items
+// that declare Permissions are hidden.
+func (m *MainLayoutPage) menuFor(c *request.Context) Menu {
+	if len(m.Menu) == 0 {
+		return nil
+	}
+	if m.Authorize == nil {
+		return m.Menu.Filter(nil)
+	}
+	return m.Menu.Filter(func(permissions []string) bool { return m.Authorize(c, permissions) })
+}
+
+// requestPath is the URL path of c, "" without a request.
+func requestPath(c *request.Context) string {
+	if c == nil || c.Request == nil || c.Request.URL == nil {
+		return ""
+	}
+	return c.Request.URL.Path
+}
@@ -1,7 +1,8 @@
 package web_render
 
 import (
-	"fmt"
+	"html/template"
+	"net/http"
 
 	"github.com/primadi/lokstra/core/request"
 )
@@ -9,43 +10,87 @@ import (
 type PageContent struct {
 	HTML        string            // Main content HTML
 	Title       string            // Page title (for browser tab and meta)
-	Description string            // Page description (for meta tags)
+	DocTitle    string            // Title after the title format, for <title>
 	MetaTags    map[string]string // Page-specific meta tags
+	SEO                           // Description, canonical, robots, social cards, JSON-LD
 	CurrentPage string            // Current page identifier (for sidebar active state)
+	Menu        Menu              // sidebar menu the request may see, full pages only
+	Params      map[string]string // route path params, e.g. "id" for /users/:id
 	SidebarData any               // Custom sidebar data if needed
+	Provided    map[string]any    // results of the layout providers, by name
+	Trace       *RenderTrace      // Resolution trail, set when TemplateLoader.Trace is on
+	Response    *HTMXResponse     // htmx response directives (HX-* headers), see HX
+	Breadcrumbs []Breadcrumb      // navbar breadcrumb, sent as a fragment
+	Flash       []FlashMessage    // flash messages, sent as a fragment
+	Fragments   []Fragment        // out-of-band fragments, see Fragment
+	Scripts     []string          // page scripts, loaded at the end of <body>
+	Styles      []string          // page stylesheets, linked in <head>
+	CustomCSS   string            // page CSS, in a <style> in <head>
+
+	composed bool       // HTML is the final response body (set by RenderPage)
+	assets   pageAssets // Scripts, Styles and CustomCSS minus what the layout loads
+}
+
+// HX returns the htmx response directives of the page, creating them on
+// first use:
+//
+//	page.HX().Trigger("userSaved", map[string]any{"id": id}).PushURL("/users/" + id)
+func (p *PageContent) HX() *HTMXResponse {
+	if p.Response == nil {
+		p.Response = &HTMXResponse{}
+	}
+	return p.Response
 }
 
 // PageContentFunc is a function that returns complete page content
 type PageContentFunc func(*request.Context) (*PageContent, error)
 
-// RenderFullPage renders a complete HTML page with layout
+// RenderFullPage renders a complete HTML page with layout. A failing
+// renderTemplate yields an error page instead. There is no Logger to
+// report to here, so renderTemplate should log its own errors, e.g. to
+// TemplateLoader.Logger.
 func RenderFullPage(pageContent *PageContent,
 	renderTemplate func(*PageContent) (string, error)) string {
 	result, err := renderTemplate(pageContent)
 	if err != nil {
-		fmt.Printf("Template execution error: %v\n", err)
-		return fmt.Sprintf("<html><body><h1>Template Execution Error</h1><p>%v</p></body></html>", err)
+		return "<html><body><h1>Template Execution Error</h1><p>" + template.HTMLEscapeString(err.Error()) + "</p></body></html>"
 	}
 	return result
 }
 
 // RenderPartialContent renders just the content for HTMX requests
-// WITH page-specific assets for consistent behavior
+// WITH page-specific assets for consistent behavior, followed by the
+// page fragments as hx-swap-oob elements
 func RenderPartialContent(pageContent *PageContent) string {
 	content := pageContent.HTML
-
+	if !pageContent.composed {
+		if pageContent.DocTitle == "" {
+			pageContent.DocTitle = formatTitle("", pageContent, nopLogger{})
+		}
+		assets := newPageAssets(nil, pageContent.Scripts, pageContent.Styles, pageContent.CustomCSS, nil)
+		head := titleTag(pageContent.DocTitle)
+		if !assets.empty() {
+			head += assets.partial()
+		}
+		content = head + content + pageContent.oobFragments()
+	}
 	return content
 }
 
 // PageHandler creates a handler with consistent behavior for both full page and HTMX requests
+//
+// Deprecated: use MainLayoutPage.Handler, which renders through the
+// layout chain, streams, and sends errors to the error page.
 func PageHandler(contentFunc PageContentFunc, renderTemplate func(*PageContent) (string, error)) func(*request.Context) error {
 	return func(c *request.Context) error {
 		pageContent, err := contentFunc(c)
 		if err != nil {
 			return err
 		}
-		isHTMXRequest := c.GetHeader("HX-Request") == "true"
-		if isHTMXRequest {
+		if err := pageContent.Response.Apply(c.Writer.Header()); err != nil {
+			return err
+		}
+		if HTMX(c).IsPartial() {
 			html := RenderPartialContent(pageContent)
 			return c.HTML(html)
 		}
@@ -53,3 +98,28 @@ func PageHandler(contentFunc PageContentFunc, renderTemplate func(*PageContent)
 		return c.HTML(fullPageHTML)
 	}
 }
+
+// HTTPPageContentFunc is PageContentFunc for raw http.Handler code.
+type HTTPPageContentFunc func(*http.Request) (*PageContent, error)
+
+// HTTPPageHandler is PageHandler for raw http.Handler code, with the same
+// full/partial decision and htmx response headers.
+func HTTPPageHandler(contentFunc HTTPPageContentFunc, renderTemplate func(*PageContent) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pageContent, err := contentFunc(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := pageContent.Response.Apply(w.Header()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		html := RenderPartialContent(pageContent)
+		if !HTMXFromRequest(r).IsPartial() {
+			html = RenderFullPage(pageContent, renderTemplate)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(html))
+	}
+}
@@ -0,0 +1,219 @@
+package web_render
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/primadi/lokstra"
+	"github.com/primadi/lokstra/core/request"
+)
+
+// paramSegmentRe matches a dynamic file or dir name, e.g. "[id]".
+var paramSegmentRe = regexp.MustCompile(`^\[([A-Za-z_][A-Za-z0-9_]*)\]$`)
+
+// FileRoute is a route derived from a page file under PageDir.
+type FileRoute struct {
+	Path     string   // route path, e.g. "/users/:id"
+	Template string   // page template name, e.g. "users/[id]"
+	Params   []string // path param names, in order
+}
+
+// FileRoutes maps the page files of a layout's loader to routes:
+//
+//	pages/index.html        -> /
+//	pages/users/index.html  -> /users
+//	pages/users/[id].html   -> /users/:id
+//	pages/[org]/team.html   -> /:org/team
+//
+// Files and dirs starting with "_" are skipped, and so is errors/, where
+// projects override the error pages (see DefaultErrorTemplates). Routes
+// come from the project layers only (the framework layer has no pages); a
+// new file needs a restart to get its route. Two files of one layer with
+// the same route, e.g. pages/users.html and pages/users/index.html, are an
+// error. The router cannot tell a static segment from a dynamic sibling,
+// so pages/users/new.html next to pages/users/[id].html is an error too;
+// move one of them a level down, e.g. pages/users/id/[id].html. Mount it
+// on an app:
+//
+//	routes, err := web_render.NewFileRoutes(layout)
+//	routes.Data("/users/:id", loadUser)
+//	if err := routes.Mount(app); err != nil { ... }
+//
+// A page without a data loader executes with its params as a map, so
+// pages/users/[id].html can use {{.id}}; with a loader it gets the loader's
+// result. Either way layouts see the params as {{.Params}}.
+type FileRoutes struct {
+	Layout *MainLayoutPage
+	Routes []FileRoute // sorted by path
+
+	data map[string]PageDataFunc
+}
+
+// NewFileRoutes scans the page dirs of layout.Loader.
+func NewFileRoutes(layout *MainLayoutPage) (*FileRoutes, error) {
+	routes, err := layout.Loader.FileRoutes()
+	if err != nil {
+		return nil, err
+	}
+	return &FileRoutes{
+		Layout: layout,
+		Routes: routes,
+		data:   map[string]PageDataFunc{},
+	}, nil
+}
+
+// Data registers the data loader of the page at routePath, e.g. "/users/:id".
+func (r *FileRoutes) Data(routePath string, fn PageDataFunc) *FileRoutes {
+	r.data[routePath] = fn
+	return r
+}
+
+// Handler returns the handler serving route.
+func (r *FileRoutes) Handler(route FileRoute) request.HandlerFunc {
+	load := r.data[route.Path]
+	return r.Layout.Handler(route.Template, func(c *request.Context, opts *PageOptions) (any, error) {
+		opts.Params = make(map[string]string, len(route.Params))
+		for _, name := range route.Params {
+			opts.Params[name] = c.GetPathParam(name)
+		}
+		if load == nil {
+			return opts.Params, nil
+		}
+		return load(c, opts)
+	}, nil)
+}
+
+// Mount adds a GET route to app for every page. It fails when two routes
+// conflict, or when a data loader is registered for a path no page file
+// maps to.
+func (r *FileRoutes) Mount(app *lokstra.App) error {
+	if err := routeConflicts(r.Routes); err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(r.Routes))
+	for _, route := range r.Routes {
+		known[route.Path] = true
+	}
+	for p := range r.data {
+		if !known[p] {
+			return fmt.Errorf("file routes: data loader for %s has no page file", p)
+		}
+	}
+	for _, route := range r.Routes {
+		r.Layout.addRoute(route.Path, "")
+		app.GET(route.Path, r.Handler(route))
+	}
+	return nil
+}
+
+// FileRoutes lists the routes of the page files in the project layers. A
+// page in an earlier layer hides one with the same route in a later layer;
+// two pages of one layer with the same route are an error.
+func (l *TemplateLoader) FileRoutes() ([]FileRoute, error) {
+	var routes []FileRoute
+	seen := map[string]bool{}
+	for _, layer := range l.Layers {
+		inLayer := map[string]string{} // route path -> template
+		var dup error
+		dir := l.pageDir(layer)
+		if layer.FS == nil || dir == l.layoutDir(layer) {
+			continue
+		}
+		_ = fs.WalkDir(layer.FS, dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if p != dir && strings.HasPrefix(d.Name(), "_") || d.IsDir() && p == path.Join(dir, "errors") {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || path.Ext(p) != ".html" {
+				return nil
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(p, dir+"/"), ".html")
+			route, ok := fileRoute(name)
+			if !ok {
+				l.logger().Warnf("page %s: unsupported file name for a route, skipped", p)
+				return nil
+			}
+			if other, ok := inLayer[route.Path]; ok {
+				dup = fmt.Errorf("file routes: %s and %s in the %s layer both map to %s", other, name, layer.Name, route.Path)
+				return fs.SkipAll
+			}
+			inLayer[route.Path] = name
+			if !seen[route.Path] {
+				routes = append(routes, route)
+			}
+			return nil
+		})
+		if dup != nil {
+			return nil, dup
+		}
+		for p := range inLayer {
+			seen[p] = true
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routeSortKey(routes[i].Path) < routeSortKey(routes[j].Path)
+	})
+	return routes, nil
+}
+
+// fileRoute maps a page name like "users/[id]" to its route.
+func fileRoute(name string) (FileRoute, bool) {
+	route := FileRoute{Template: name}
+	var segments []string
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if i == len(parts)-1 && part == "index" {
+			break
+		}
+		if m := paramSegmentRe.FindStringSubmatch(part); m != nil {
+			route.Params = append(route.Params, m[1])
+			segments = append(segments, ":"+m[1])
+			continue
+		}
+		if part == "" || strings.ContainsAny(part, "[]:*") {
+			return FileRoute{}, false
+		}
+		segments = append(segments, part)
+	}
+	route.Path = "/" + strings.Join(segments, "/")
+	return route, true
+}
+
+// routeConflicts reports the first pair of routes the router would reject:
+// the same path, or siblings where one segment is dynamic and the other is
+// static or dynamic under another name ("/users/new" and "/users/:id").
+func routeConflicts(routes []FileRoute) error {
+	for i, a := range routes {
+		as := pathSegments(a.Path)
+		for _, b := range routes[i+1:] {
+			bs := pathSegments(b.Path)
+			for k := 0; k < len(as) && k < len(bs); k++ {
+				if as[k] == bs[k] {
+					if k == len(as)-1 && len(as) == len(bs) {
+						return fmt.Errorf("file routes: %s and %s both map to %s", a.Template, b.Template, a.Path)
+					}
+					continue
+				}
+				if strings.HasPrefix(as[k], ":") || strings.HasPrefix(bs[k], ":") {
+					return fmt.Errorf("file routes: %s (%s) conflicts with %s (%s)", a.Path, a.Template, b.Path, b.Template)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// routeSortKey orders static segments before dynamic ones at each level.
+func routeSortKey(p string) string {
+	return strings.ReplaceAll(p, ":", "\xff")
+}
@@ -0,0 +1,114 @@
+package web_render
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params []string
+		ok     bool
+	}{
+		{name: "index", path: "/", ok: true},
+		{name: "users", path: "/users", ok: true},
+		{name: "users/index", path: "/users", ok: true},
+		{name: "users/[id]", path: "/users/:id", params: []string{"id"}, ok: true},
+		{name: "[org]/team", path: "/:org/team", params: []string{"org"}, ok: true},
+		{name: "[org]/users/[id]", path: "/:org/users/:id", params: []string{"org", "id"}, ok: true},
+		{name: "index/about", path: "/index/about", ok: true},
+		{name: "users/[1id]"},
+		{name: "users/[id"},
+		{name: "users/a:b"},
+		{name: "users/[...rest]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, ok := fileRoute(tt.name)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if route.Path != tt.path || !slices.Equal(route.Params, tt.params) || route.Template != tt.name {
+				t.Errorf("fileRoute(%q) = %+v, want path %s params %v", tt.name, route, tt.path, tt.params)
+			}
+		})
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		wantErr string
+	}{
+		{name: "distinct", paths: []string{"/", "/users", "/users/:id", "/users/:id/edit", "/teams"}},
+		{name: "static under dynamic parents", paths: []string{"/:org/team", "/:org/users"}},
+		{name: "static and dynamic siblings", paths: []string{"/users/new", "/users/:id"}, wantErr: "conflicts"},
+		{name: "differently named params", paths: []string{"/users/:id", "/users/:slug"}, wantErr: "conflicts"},
+		{name: "dynamic root sibling", paths: []string{"/about", "/:org"}, wantErr: "conflicts"},
+		{name: "same path", paths: []string{"/users", "/users"}, wantErr: "both map to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var routes []FileRoute
+			for _, p := range tt.paths {
+				routes = append(routes, FileRoute{Path: p, Template: strings.TrimPrefix(p, "/")})
+			}
+			err := routeConflicts(routes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoaderFileRoutes(t *testing.T) {
+	page := &fstest.MapFile{Data: []byte(`page`)}
+	project := fstest.MapFS{
+		"pages/index.html":          page,
+		"pages/users/index.html":    page,
+		"pages/users/[id].html":     page,
+		"pages/_drafts/new.html":    page,
+		"pages/users/_form.html":    page,
+		"pages/errors/404.html":     page,
+		"pages/reports/errors.html": page,
+		"pages/notes.txt":           page,
+		"layouts/base.html":         page,
+		"partials/users-table.html": page,
+	}
+	theme := fstest.MapFS{
+		"pages/users.html": page, // hidden by the project's users/index.html
+		"pages/about.html": page,
+	}
+	l := NewTemplateLoaderFS(TemplateLayer{Name: "project", FS: project}, TemplateLayer{Name: "theme", FS: theme})
+	routes, err := l.FileRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range routes {
+		got = append(got, r.Path+" "+r.Template)
+	}
+	want := []string{"/ index", "/about about", "/reports/errors reports/errors", "/users users/index", "/users/:id users/[id]"}
+	if !slices.Equal(got, want) {
+		t.Errorf("routes = %q, want %q", got, want)
+	}
+
+	project["pages/users.html"] = page
+	if _, err := l.FileRoutes(); err == nil || !strings.Contains(err.Error(), "both map to /users") {
+		t.Errorf("users.html next to users/index.html: err = %v", err)
+	}
+}
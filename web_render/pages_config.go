@@ -0,0 +1,323 @@
+package web_render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/primadi/lokstra"
+	"github.com/primadi/lokstra/core/config"
+	"github.com/primadi/lokstra/core/request"
+	"gopkg.in/yaml.v3"
+)
+
+// PageConfig is one entry of a pages: section in the lokstra YAML config,
+// next to routes: in an app or group:
+//
+//	apps:
+//	  - name: "admin"
+//	    pages:
+//	      - path: "/"
+//	        template: "dashboard"
+//	        title: "Dashboard"
+//	    groups:
+//	      - prefix: "/users"
+//	        pages:
+//	          - path: "/id/:id"
+//	            handler: "users.detail.page"
+//	            template: "users/detail"
+//	            layout: "admin.html"
+//	            data: "users.detail"
+//	            permissions: ["users.read"]
+//
+// ConfigPages.Apply turns every entry into a GET route of its app or
+// group, so it takes that group's middleware like the routes next to it.
+type PageConfig struct {
+	Path        string   `yaml:"path"`         // full path after loading, group prefixes included
+	Handler     string   `yaml:"handler"`      // registered handler name, default "page:" + Path
+	Template    string   `yaml:"template"`     // page template, without .html
+	Layout      string   `yaml:"layout"`       // layout overriding the page's own and the main layout
+	Data        string   `yaml:"data"`         // data provider name, see ConfigPages.Data
+	Title       string   `yaml:"title"`        // page title
+	CurrentPage string   `yaml:"current_page"` // sidebar active item
+	Permissions []string `yaml:"permissions"`  // all required, checked by ConfigPages.Authorize
+	App         string   `yaml:"-"`            // name of the app the entry belongs to
+
+	groups []string // prefixes of the enclosing groups, outermost first
+	local  string   // path as declared, relative to the innermost group
+}
+
+// routeConfig is a routes: entry, read so Apply skips pages that already
+// have a route.
+type routeConfig struct {
+	Method  string `yaml:"method"`
+	Path    string `yaml:"path"`
+	Handler string `yaml:"handler"`
+}
+
+// pagesNode is the part of an app or group that matters for pages.
+type pagesNode struct {
+	Name     string        `yaml:"name"`
+	Prefix   string        `yaml:"prefix"`
+	Routes   []routeConfig `yaml:"routes"`
+	Pages    []PageConfig  `yaml:"pages"`
+	Groups   []pagesNode   `yaml:"groups"`
+	LoadFrom []string      `yaml:"load_from"`
+}
+
+type pagesFile struct {
+	Apps []pagesNode `yaml:"apps"`
+}
+
+// ConfigPages are the pages declared in a lokstra config directory, bound
+// to a layout. Add their routes to the config before the server is built
+// from it, and register their handlers:
+//
+//	cfg, err := lokstra.LoadConfigDir("config/")
+//	pages, err := web_render.LoadPagesConfig(layout, "config/")
+//	pages.Data("users.detail", loadUserDetail)
+//	pages.Authorize = hasPermissions
+//	if err := pages.Apply(cfg); err != nil { ... }
+//	if err := pages.Register(regCtx); err != nil { ... }
+//	server, err := lokstra.NewServerFromConfig(regCtx, cfg)
+//
+// Or mount them on an app built in code with Mount.
+type ConfigPages struct {
+	Layout *MainLayoutPage
+	Pages  []PageConfig
+
+	// Authorize reports whether the request may see a page with the given
+	// permissions; a false answer renders the 403 page. Defaults to
+	// Layout.Authorize; one of them is required when any page declares
+	// permissions.
+	Authorize func(c *request.Context, permissions []string) bool
+
+	data   map[string]PageDataFunc
+	routes map[string][]string // GET route paths by app + " " + handler
+}
+
+// LoadPagesConfig reads the pages: sections of every app, and of their
+// groups (load_from files included), from the YAML files in dir, the same
+// directory lokstra.LoadConfigDir reads. lokstra itself ignores the
+// pages: key.
+func LoadPagesConfig(layout *MainLayoutPage, dir string) (*ConfigPages, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	more, _ := filepath.Glob(filepath.Join(dir, "*.yml"))
+	files = append(files, more...)
+	sort.Strings(files)
+
+	p := &ConfigPages{Layout: layout, data: map[string]PageDataFunc{}, routes: map[string][]string{}}
+	for _, file := range files {
+		var f pagesFile
+		if err := readYAML(file, &f); err != nil {
+			return nil, err
+		}
+		for _, app := range f.Apps {
+			if err := p.collect(dir, app.Name, "", nil, app, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// collect adds the pages of node and its groups under prefix; groups are
+// the prefixes of node and the groups around it.
+func (p *ConfigPages) collect(dir, app, prefix string, groups []string, node pagesNode, depth int) error {
+	if depth > 32 {
+		return fmt.Errorf("pages config: groups nested too deep under %q", prefix)
+	}
+	prefix += node.Prefix
+	for _, file := range node.LoadFrom {
+		var sub pagesNode
+		if err := readYAML(filepath.Join(dir, file), &sub); err != nil {
+			return err
+		}
+		node.Routes = append(node.Routes, sub.Routes...)
+		node.Pages = append(node.Pages, sub.Pages...)
+		node.Groups = append(node.Groups, sub.Groups...)
+	}
+	for _, r := range node.Routes {
+		if r.Method == "" || strings.EqualFold(r.Method, "GET") {
+			key := app + " " + r.Handler
+			p.routes[key] = append(p.routes[key], routePath(prefix+r.Path))
+		}
+	}
+	for _, page := range node.Pages {
+		page.App = app
+		page.groups = groups
+		page.local = page.Path
+		page.Path = routePath(prefix + page.Path)
+		if page.Handler == "" {
+			page.Handler = "page:" + page.Path
+		}
+		if page.Template == "" {
+			return fmt.Errorf("pages config: page %s in app %s has no template", page.Path, app)
+		}
+		p.Pages = append(p.Pages, page)
+	}
+	for _, group := range node.Groups {
+		if err := p.collect(dir, app, prefix, append(slices.Clip(groups), group.Prefix), group, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routePath is p with a leading slash and no trailing one.
+func routePath(p string) string {
+	return "/" + strings.Trim(p, "/")
+}
+
+func readYAML(file string, v any) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("pages config %s: %w", file, err)
+	}
+	return nil
+}
+
+// Data registers the data provider pages refer to by name in data:.
+func (p *ConfigPages) Data(name string, fn PageDataFunc) *ConfigPages {
+	p.data[name] = fn
+	return p
+}
+
+// Handler returns the handler serving page.
+func (p *ConfigPages) Handler(page PageConfig) (request.HandlerFunc, error) {
+	var data PageDataFunc
+	if page.Data != "" {
+		if data = p.data[page.Data]; data == nil {
+			return nil, fmt.Errorf("page %s: data provider %q is not registered", page.Path, page.Data)
+		}
+	}
+	authorize := p.Authorize
+	if authorize == nil {
+		authorize = p.Layout.Authorize
+	}
+	if len(page.Permissions) > 0 && authorize == nil {
+		return nil, fmt.Errorf("page %s declares permissions but no Authorize is set", page.Path)
+	}
+	if _, err := p.Layout.Loader.Resolve(page.Template + ".html"); err != nil {
+		return nil, fmt.Errorf("page %s: %w", page.Path, err)
+	}
+	if page.Layout != "" {
+		if _, err := p.Layout.Loader.Resolve(page.Layout); err != nil {
+			return nil, fmt.Errorf("page %s: %w", page.Path, err)
+		}
+	}
+
+	opts := PageOptions{Title: page.Title, CurrentPage: page.CurrentPage, Layout: page.Layout}
+	render := p.Layout.Handler(page.Template, data, &opts)
+	if len(page.Permissions) == 0 {
+		return render, nil
+	}
+	perms := page.Permissions
+	return func(c *request.Context) error {
+		if !authorize(c, perms) {
+			return p.Layout.RenderError(c, Forbidden(""))
+		}
+		return render(c)
+	}, nil
+}
+
+// Apply adds a GET route for every page to cfg, as loaded by
+// lokstra.LoadConfigDir, in the app or group that declares the page; pages
+// that already have a GET route with their handler at their path keep it.
+// Groups are found by their prefixes, so a group loaded with load_from
+// must keep the prefix it has in the files.
+func (p *ConfigPages) Apply(cfg *config.LokstraConfig) error {
+	for _, page := range p.Pages {
+		key := page.App + " " + page.Handler
+		if slices.Contains(p.routes[key], page.Path) {
+			continue
+		}
+		route := config.RouteConfig{Method: "GET", Path: page.local, Handler: page.Handler}
+		if len(page.groups) > 0 && strings.Trim(route.Path, "/") == "" {
+			route.Path = "" // the group root, as in routes:
+		}
+		added := false
+		for i := range cfg.Apps {
+			if cfg.Apps[i].Name != page.App {
+				continue
+			}
+			if len(page.groups) == 0 {
+				cfg.Apps[i].Routes = append(cfg.Apps[i].Routes, route)
+				added = true
+			} else {
+				added = addGroupRoute(cfg.Apps[i].Groups, page.groups, route)
+			}
+			break
+		}
+		if !added {
+			return fmt.Errorf("page %s: app %s has no group %s in the lokstra config", page.Path, page.App, strings.Join(page.groups, " > "))
+		}
+		p.routes[key] = append(p.routes[key], page.Path)
+	}
+	return nil
+}
+
+// addGroupRoute adds route to the group reached through prefixes.
+func addGroupRoute(groups []config.GroupConfig, prefixes []string, route config.RouteConfig) bool {
+	for i := range groups {
+		if groups[i].Prefix != prefixes[0] {
+			continue
+		}
+		if len(prefixes) == 1 {
+			groups[i].Routes = append(groups[i].Routes, route)
+			return true
+		}
+		if addGroupRoute(groups[i].Groups, prefixes[1:], route) {
+			return true
+		}
+	}
+	return false
+}
+
+// Register registers every page handler under its Handler name. It fails
+// on the first page with a missing template, layout or data provider, or
+// without a GET route naming its handler at its path; Apply adds those.
+func (p *ConfigPages) Register(regCtx lokstra.RegistrationContext) error {
+	for _, page := range p.Pages {
+		h, err := p.Handler(page)
+		if err != nil {
+			return err
+		}
+		paths := p.routes[page.App+" "+page.Handler]
+		if !slices.Contains(paths, page.Path) {
+			if len(paths) > 0 {
+				return fmt.Errorf("page %s: the routes: entry for handler %q is at %s, not %s", page.Path, page.Handler, strings.Join(paths, ", "), page.Path)
+			}
+			return fmt.Errorf("page %s: app %s has no GET route with handler %q; call Apply on the lokstra config first", page.Path, page.App, page.Handler)
+		}
+		p.Layout.addRoute(page.Path, page.Title)
+		regCtx.RegisterHandler(page.Handler, h)
+	}
+	return nil
+}
+
+// Mount adds a GET route to app for every page of the app named appName,
+// or of every app when appName is empty.
+func (p *ConfigPages) Mount(app *lokstra.App, appName string) error {
+	for _, page := range p.Pages {
+		if appName != "" && page.App != appName {
+			continue
+		}
+		h, err := p.Handler(page)
+		if err != nil {
+			return err
+		}
+		p.Layout.addRoute(page.Path, page.Title)
+		app.GET(page.Path, h)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package web_render
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/primadi/lokstra/core/config"
+	"github.com/primadi/lokstra/core/request"
+)
+
+const testPagesYAML = `
+apps:
+  - name: "admin"
+    routes:
+      - method: "GET"
+        path: "/about"
+        handler: "page:/about"
+    pages:
+      - path: "/"
+        template: "dashboard"
+      - path: "/about"
+        template: "dashboard"
+    groups:
+      - prefix: "/users"
+        pages:
+          - path: "/"
+            template: "dashboard"
+          - path: "/id/:id"
+            handler: "users.detail.page"
+            template: "users/detail"
+`
+
+type testRegistry map[string]request.HandlerFunc
+
+func (r testRegistry) RegisterHandler(name string, h request.HandlerFunc) { r[name] = h }
+
+func TestConfigPagesApply(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.yaml"), []byte(testPagesYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layout := testLayout(map[string]string{"dashboard": `dashboard`, "users/detail": `{{.}}`})
+	pages, err := LoadPagesConfig(layout, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pages.Register(testRegistry{}); err == nil {
+		t.Error("Register before Apply: want a missing route error")
+	}
+
+	cfg := &config.LokstraConfig{Apps: []*config.AppConfig{{
+		Name:   "admin",
+		Routes: []config.RouteConfig{{Method: "GET", Path: "/about", Handler: "page:/about"}},
+		Groups: []config.GroupConfig{{Prefix: "/users"}},
+	}}}
+	if err := pages.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+	app := cfg.Apps[0]
+	wantApp := []config.RouteConfig{
+		{Method: "GET", Path: "/about", Handler: "page:/about"},
+		{Method: "GET", Path: "/", Handler: "page:/"},
+	}
+	if len(app.Routes) != len(wantApp) || app.Routes[0] != wantApp[0] || app.Routes[1] != wantApp[1] {
+		t.Errorf("app routes = %+v, want %+v", app.Routes, wantApp)
+	}
+	wantGroup := []config.RouteConfig{
+		{Method: "GET", Path: "", Handler: "page:/users"},
+		{Method: "GET", Path: "/id/:id", Handler: "users.detail.page"},
+	}
+	got := app.Groups[0].Routes
+	if len(got) != len(wantGroup) || got[0] != wantGroup[0] || got[1] != wantGroup[1] {
+		t.Errorf("group routes = %+v, want %+v", got, wantGroup)
+	}
+
+	reg := testRegistry{}
+	if err := pages.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"page:/", "page:/about", "page:/users", "users.detail.page"} {
+		if reg[name] == nil {
+			t.Errorf("handler %s not registered", name)
+		}
+	}
+
+	pages, err = LoadPagesConfig(layout, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := &config.LokstraConfig{Apps: []*config.AppConfig{{Name: "admin"}}}
+	if err := pages.Apply(missing); err == nil {
+		t.Error("Apply without the /users group: want an error")
+	}
+}
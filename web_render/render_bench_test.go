@@ -0,0 +1,118 @@
+package web_render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type benchUser struct {
+	ID    int
+	Name  string
+	Email string
+	Role  string
+}
+
+// benchLayout is a two-level layout chain around a 500-row users table,
+// the shape of the dashboard users page.
+func benchLayout(b *testing.B) (*MainLayoutPage, []benchUser) {
+	b.Helper()
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<!DOCTYPE html><html><head><title>{{.Title}}</title></head>` +
+			`<body><nav>{{.CurrentPage}}</nav><main>{{.Content}}</main></body></html>`)},
+		"layouts/admin.html": {Data: []byte(`{{/* layout: base.html */}}<section class="admin">{{.Content}}</section>`)},
+		"pages/users.html": {Data: []byte(`{{/* layout: admin.html */}}<table>` +
+			`{{range .}}<tr><td>{{.ID}}</td><td>{{.Name}}</td><td>{{.Email}}</td><td>{{.Role}}</td></tr>{{end}}</table>`)},
+	}
+	loader := NewTemplateLoaderFS(TemplateLayer{Name: "bench", FS: fsys})
+	loader.SetMode(ModeProduction)
+	users := make([]benchUser, 500)
+	for i := range users {
+		users[i] = benchUser{ID: i, Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i), Role: "member"}
+	}
+	m := NewMainLayoutPageWithLoader("base.html", loader)
+	if _, err := m.RenderPage(nil, "users", users, nil); err != nil {
+		b.Fatal(err)
+	}
+	return m, users
+}
+
+// BenchmarkRenderPage renders into a string, then copies it into the
+// response the way c.HTML(page.HTML) does.
+func BenchmarkRenderPage(b *testing.B) {
+	m, users := benchLayout(b)
+	opts := &PageOptions{Title: "Users", CurrentPage: "users"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		page, err := m.RenderPage(nil, "users", users, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.WriteString(io.Discard, page.HTML); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderPageTo streams the outermost layout into the writer.
+func BenchmarkRenderPageTo(b *testing.B) {
+	m, users := benchLayout(b)
+	opts := &PageOptions{Title: "Users", CurrentPage: "users"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.RenderPageTo(io.Discard, nil, "users", users, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderPagePartial is the htmx fragment path: the page alone,
+// streamed without any layout.
+func BenchmarkRenderPagePartial(b *testing.B) {
+	m, users := benchLayout(b)
+	opts := &PageOptions{Partial: true}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.RenderPageTo(io.Discard, nil, "users", users, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderPageStringBuilder is the baseline the pooled path
+// replaced: every level executes into its own strings.Builder and is
+// re-wrapped as template.HTML for the next.
+func BenchmarkRenderPageStringBuilder(b *testing.B) {
+	m, users := benchLayout(b)
+	set, err := m.Loader.loadPage("base.html", "users", false, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf strings.Builder
+		if err := set.tmpl.ExecuteTemplate(&buf, "users.html", users); err != nil {
+			b.Fatal(err)
+		}
+		html := buf.String()
+		data := LayoutData{PageContent: PageContent{Title: "Users", CurrentPage: "users"}, Data: users}
+		for _, name := range set.layouts {
+			data.Content = template.HTML(html)
+			var buf strings.Builder
+			if err := set.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+				b.Fatal(err)
+			}
+			html = buf.String()
+		}
+		if _, err := io.WriteString(io.Discard, html); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
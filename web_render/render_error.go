@@ -0,0 +1,133 @@
+package web_render
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// Template error kinds, for errors.Is:
+//
+//	if errors.Is(err, web_render.ErrTemplateNotFound) { ... }
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateParse    = errors.New("template parse error")
+	ErrTemplateExecute  = errors.New("template execute error")
+)
+
+// TemplateError describes a template that could not be found, parsed or
+// executed. Line and Expr are filled from the html/template error when it
+// carries them.
+type TemplateError struct {
+	Kind    error  // ErrTemplateNotFound, ErrTemplateParse or ErrTemplateExecute
+	Name    string // template name, e.g. "users.html" or "partials/users-table"
+	Layer   string // layer the file came from, when known
+	Path    string // path inside the layer, when known
+	Line    int    // 1-based line, 0 when unknown
+	Expr    string // failing expression for execute errors, e.g. ".User.Nmae"
+	Message string // error description without the location prefix
+	Err     error  // underlying error
+}
+
+func (e *TemplateError) Error() string {
+	loc := e.Name
+	if e.Path != "" {
+		loc = e.Path
+	}
+	if e.Line > 0 {
+		loc += ":" + strconv.Itoa(e.Line)
+	}
+	switch e.Kind {
+	case ErrTemplateNotFound:
+		return fmt.Sprintf("template %s not found in any layer or framework", e.Name)
+	case ErrTemplateExecute:
+		if e.Expr != "" {
+			return fmt.Sprintf("execute %s at <%s>: %s", loc, e.Expr, e.Message)
+		}
+		return fmt.Sprintf("execute %s: %s", loc, e.Message)
+	default:
+		return fmt.Sprintf("parse %s: %s", loc, e.Message)
+	}
+}
+
+func (e *TemplateError) Unwrap() error { return e.Err }
+
+func (e *TemplateError) Is(target error) bool { return target == e.Kind }
+
+// templateErrRe matches html/template errors such as
+// `template: users.html:12:5: executing "users.html" at <.User.Nmae>: can't evaluate ...`,
+// `template: users.html:12: function "foo" not defined` and escaper errors
+// like `html/template:users.html:3:14: {{.X}} appears in an ambiguous context`.
+var templateErrRe = regexp.MustCompile(`(?s)^(?:html/)?template: ?([^:\s]+)(?::(\d+))?(?::\d+)?: (?:executing "[^"]*" at <(.*?)>: )?(.*)$`)
+
+// newTemplateError wraps an html/template error of the given kind raised
+// for template name, extracting the location the error points at.
+func newTemplateError(kind error, name string, err error) *TemplateError {
+	te := &TemplateError{Kind: kind, Name: name, Message: err.Error(), Err: err}
+	if m := templateErrRe.FindStringSubmatch(err.Error()); m != nil {
+		te.Name = m[1]
+		te.Line, _ = strconv.Atoi(m[2])
+		te.Expr = m[3]
+		te.Message = m[4]
+	}
+	return te
+}
+
+// HTTPError is an error with an HTTP status, returned by handlers and data
+// providers to get the matching error page:
+//
+//	if user == nil {
+//		return web_render.NotFound("user not found")
+//	}
+type HTTPError struct {
+	Status  int
+	Message string // shown on the error page
+	Err     error  // optional cause, logged but never shown
+}
+
+// NewHTTPError returns an HTTPError with status and a user-facing message.
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// NotFound returns a 404 HTTPError.
+func NotFound(message string) *HTTPError {
+	return NewHTTPError(http.StatusNotFound, message)
+}
+
+// Forbidden returns a 403 HTTPError.
+func Forbidden(message string) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, message)
+}
+
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, msg, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, msg)
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// ErrorStatus returns the HTTP status for err: the status of an HTTPError,
+// 500 for anything else.
+func ErrorStatus(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) && he.Status != 0 {
+		return he.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorData is the data error templates execute with.
+type ErrorData struct {
+	Status     int
+	StatusText string
+	Message    string // HTTPError message; empty for internal errors
+}
@@ -0,0 +1,111 @@
+package web_render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"testing"
+)
+
+// templateErr returns the error html/template gives for src, parsed as
+// name and executed with data.
+func templateErr(t *testing.T, name, src string, data any) error {
+	t.Helper()
+	tmpl, err := template.New(name).Parse(src)
+	if err != nil {
+		return err
+	}
+	err = tmpl.ExecuteTemplate(io.Discard, name, data)
+	if err == nil {
+		t.Fatalf("%s: no error", name)
+	}
+	return err
+}
+
+func TestNewTemplateError(t *testing.T) {
+	type user struct{ Name string }
+	tests := []struct {
+		name string
+		err  func(t *testing.T) error
+		kind error
+		want TemplateError // Name, Line, Expr and Message
+	}{
+		{
+			name: "execute, missing field",
+			err: func(t *testing.T) error {
+				return templateErr(t, "users.html", "<ul>\n<li>{{.Nmae}}</li>\n</ul>", user{})
+			},
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "users.html", Line: 2, Expr: ".Nmae", Message: "can't evaluate field Nmae in type web_render.user"},
+		},
+		{
+			name: "execute, nested partial name",
+			err: func(t *testing.T) error {
+				return templateErr(t, "partials/users-table", "<table>\n\n{{.Rows.Count}}</table>", map[string]any{"Rows": 3})
+			},
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "partials/users-table", Line: 3, Expr: ".Rows.Count", Message: "can't evaluate field Count in type interface {}"},
+		},
+		{
+			name: "execute, nil pointer",
+			err: func(t *testing.T) error {
+				return templateErr(t, "users/detail.html", "{{.User.Name}}", struct{ User *user }{})
+			},
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "users/detail.html", Line: 1, Expr: ".User.Name", Message: "nil pointer evaluating *web_render.user.Name"},
+		},
+		{
+			name: "parse, undefined function",
+			err: func(t *testing.T) error {
+				return templateErr(t, "users.html", "<p>\n{{foo .}}</p>", nil)
+			},
+			kind: ErrTemplateParse,
+			want: TemplateError{Name: "users.html", Line: 2, Message: `function "foo" not defined`},
+		},
+		{
+			name: "parse, unclosed action in partial",
+			err: func(t *testing.T) error {
+				return templateErr(t, "partials/users-table", "<table>\n{{range .}}\n</table>", nil)
+			},
+			kind: ErrTemplateParse,
+			want: TemplateError{Name: "partials/users-table", Line: 3, Message: "unexpected EOF"},
+		},
+		{
+			name: "html/template escaper",
+			err: func(t *testing.T) error {
+				return templateErr(t, "users.html", "<p>\n{{if .}}<a href=\"{{end}}x\n", "x")
+			},
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "users.html", Line: 2},
+		},
+		{
+			name: "html/template escaper, no line",
+			err: func(t *testing.T) error {
+				return templateErr(t, "partials/users-table", "<p>\n<a href=\"{{.}}\n</p>", "x")
+			},
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "partials/users-table"},
+		},
+		{
+			name: "not a template error",
+			err:  func(t *testing.T) error { return errors.New("boom") },
+			kind: ErrTemplateExecute,
+			want: TemplateError{Name: "page.html", Message: "boom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err(t)
+			te := newTemplateError(tt.kind, "page.html", err)
+			if te.Name != tt.want.Name || te.Line != tt.want.Line || te.Expr != tt.want.Expr {
+				t.Errorf("from %q:\ngot  name %q line %d expr %q", err, te.Name, te.Line, te.Expr)
+			}
+			if tt.want.Message != "" && te.Message != tt.want.Message {
+				t.Errorf("from %q:\ngot message %q, want %q", err, te.Message, tt.want.Message)
+			}
+			if !errors.Is(te, tt.kind) || !errors.Is(te, err) {
+				t.Errorf("errors.Is does not match the kind and the cause")
+			}
+		})
+	}
+}
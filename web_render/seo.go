@@ -0,0 +1,59 @@
+package web_render
+
+import "slices"
+
+// SEO is the search and social metadata of a page, rendered into <head>
+// by the "lokstra/head" partial:
+//
+//	<head>
+//		<title>{{.Title}}</title>
+//		{{template "lokstra/head" .}}
+//	</head>
+//
+// OpenGraph and Twitter fields left empty fall back to the page title,
+// Description and Canonical.
+type SEO struct {
+	Description string       // <meta name="description">
+	Canonical   string       // <link rel="canonical">, absolute URL
+	Robots      string       // <meta name="robots">, e.g. "noindex, nofollow"
+	OpenGraph   *OpenGraph   // og:* properties
+	Twitter     *TwitterCard // twitter:* cards
+	JSONLD      []any        // structured data, each one <script type="application/ld+json">
+}
+
+// OpenGraph holds og:* properties, see https://ogp.me.
+type OpenGraph struct {
+	Title       string
+	Description string
+	Type        string // default "website"
+	URL         string
+	Image       string
+	ImageAlt    string
+	SiteName    string
+	Locale      string
+}
+
+// TwitterCard holds twitter:* properties.
+type TwitterCard struct {
+	Card        string // summary (default), summary_large_image, ...
+	Site        string // @account of the site
+	Creator     string // @account of the author
+	Title       string
+	Description string
+	Image       string
+	ImageAlt    string
+}
+
+// clone copies s with its own OpenGraph, Twitter and JSONLD slice.
+func (s SEO) clone() SEO {
+	if s.OpenGraph != nil {
+		og := *s.OpenGraph
+		s.OpenGraph = &og
+	}
+	if s.Twitter != nil {
+		tw := *s.Twitter
+		s.Twitter = &tw
+	}
+	s.JSONLD = slices.Clone(s.JSONLD)
+	return s
+}
@@ -0,0 +1,199 @@
+package web_render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSlotTimeout bounds a Slot without its own Timeout.
+const DefaultSlotTimeout = 5 * time.Second
+
+// Slot is a slow section of a page that is rendered after the rest of it.
+// The page renders its own placeholder element with the slot ID; the layout
+// and page are flushed right away while every slot's Load runs
+// concurrently, and each slot is streamed into the same response as soon
+// as it is ready:
+//
+//	<div id="activity" aria-busy="true">Loading recent activity...</div>
+//
+//	opts.Slots = []web_render.Slot{{
+//		ID:       "activity",
+//		Template: "partials/activity",
+//		Load:     func(ctx context.Context) (any, error) { return loadActivity(ctx) },
+//		Timeout:  2 * time.Second,
+//	}}
+//
+// The placeholder's children are replaced: via hx-swap-oob in htmx
+// responses, via a small inline script on full page loads. On full pages
+// the slots go out before the layout's last </body>; the page up to there
+// is flushed first and the rest follows the last slot.
+type Slot struct {
+	ID       string                                 // id of the placeholder element
+	Template string                                 // executed with the data Load returns; may be a {{define}} in the page
+	Load     func(ctx context.Context) (any, error) // the slow part
+	Timeout  time.Duration                          // default DefaultSlotTimeout
+	Fallback string                                 // executed with a SlotFallback on error or timeout, default "partials/lokstra/slot-error"
+}
+
+// SlotFallback is the data a slot fallback template executes with.
+type SlotFallback struct {
+	ID       string
+	TimedOut bool
+}
+
+type slotResult struct {
+	slot Slot
+	data any
+	err  error
+}
+
+// startSlots runs every slot's Load in its own goroutine. Results arrive on
+// the returned channel in completion order; it is buffered so loaders never
+// block, even when rendering is abandoned.
+func startSlots(ctx context.Context, slots []Slot) <-chan slotResult {
+	results := make(chan slotResult, len(slots))
+	for _, s := range slots {
+		go func(s Slot) {
+			timeout := s.Timeout
+			if timeout <= 0 {
+				timeout = DefaultSlotTimeout
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			done := make(chan slotResult, 1)
+			go func() {
+				if s.Load == nil {
+					done <- slotResult{slot: s}
+					return
+				}
+				data, err := s.Load(ctx)
+				done <- slotResult{slot: s, data: data, err: err}
+			}()
+			select {
+			case r := <-done:
+				results <- r
+			case <-ctx.Done():
+				results <- slotResult{slot: s, err: ctx.Err()}
+			}
+		}(s)
+	}
+	return results
+}
+
+var (
+	slotScriptOnce sync.Once
+	slotScript     string
+)
+
+// slotRuntime returns the inline script that moves streamed slots into
+// their placeholders on full page loads.
+func slotRuntime() string {
+	slotScriptOnce.Do(func() {
+		fw := DefaultFrameworkAssetLoader()
+		if b, err := fs.ReadFile(fw.FS, path.Join(fw.JsDir, "slots.js")); err == nil {
+			slotScript = "<script>" + string(b) + "</script>"
+		}
+	})
+	return slotScript
+}
+
+// streamSlots writes each slot as it completes, flushing after every one.
+// tmpl is the page's template set, so slot templates can be partials or
+// {{define}} blocks of the page.
+func (m *MainLayoutPage) streamSlots(w io.Writer, tmpl *template.Template, results <-chan slotResult, n int, partial bool, tr *RenderTrace) error {
+	log := m.Loader.logger()
+	flush(w)
+	if !partial {
+		if _, err := io.WriteString(w, slotRuntime()); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < n; i++ {
+		r := <-results
+		var buf strings.Builder
+		err := r.err
+		if err == nil {
+			err = executeTraced(tmpl, &buf, r.slot.Template, r.data, tr)
+		}
+		if err != nil {
+			log.Warnf("slot %s: %v", r.slot.ID, err)
+			fallback := r.slot.Fallback
+			if fallback == "" {
+				fallback = "partials/lokstra/slot-error"
+			}
+			buf.Reset()
+			data := SlotFallback{ID: r.slot.ID, TimedOut: errors.Is(err, context.DeadlineExceeded)}
+			if err := executeTraced(tmpl, &buf, fallback, data, tr); err != nil {
+				log.Errorf("slot %s fallback: %v", r.slot.ID, err)
+			}
+		}
+		var out string
+		if partial {
+			out = Fragment{ID: r.slot.ID, Swap: "innerHTML", HTML: template.HTML(buf.String())}.wrap(true)
+		} else {
+			id := template.HTMLEscapeString(r.slot.ID)
+			js := template.JSEscapeString(r.slot.ID)
+			out = `<template id="ls-slot-` + id + `">` + buf.String() + `</template>` +
+				`<script>lsSwapSlot("` + js + `")</script>`
+		}
+		if _, err := io.WriteString(w, out); err != nil {
+			return err
+		}
+		flush(w)
+	}
+	return nil
+}
+
+var closeBody = []byte("</body>")
+
+// bodyTailWriter passes a full page through to w but holds back
+// everything from its last </body>, so slots stream inside the body;
+// writeTail sends the rest once they are done.
+type bodyTailWriter struct {
+	w    io.Writer
+	held []byte
+}
+
+func (b *bodyTailWriter) Write(p []byte) (int, error) {
+	b.held = append(b.held, p...)
+	cut := bytes.LastIndex(b.held, closeBody)
+	if cut < 0 {
+		// Keep a trailing "</bo" that may be completed by the next write
+		cut = len(b.held)
+		for k := min(len(closeBody)-1, len(b.held)); k > 0; k-- {
+			if bytes.HasSuffix(b.held, closeBody[:k]) {
+				cut -= k
+				break
+			}
+		}
+	}
+	if cut > 0 {
+		if _, err := b.w.Write(b.held[:cut]); err != nil {
+			return 0, err
+		}
+		b.held = append(b.held[:0], b.held[cut:]...)
+	}
+	return len(p), nil
+}
+
+func (b *bodyTailWriter) writeTail() error {
+	_, err := b.w.Write(b.held)
+	b.held = nil
+	return err
+}
+
+// flush pushes buffered output to the client when w supports it.
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,167 @@
+package web_render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBodyTailWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		passed string // written before writeTail
+	}{
+		{name: "one write", writes: []string{"<body>page</body></html>"}, passed: "<body>page"},
+		{name: "split in tag", writes: []string{"<body>page</bo", "dy></html>"}, passed: "<body>page"},
+		{name: "split after <", writes: []string{"<body>page<", "/body></html>"}, passed: "<body>page"},
+		{name: "split before >", writes: []string{"<body>page</body", "></html>"}, passed: "<body>page"},
+		{name: "byte by byte", writes: strings.Split("<p>x</p></body>", ""), passed: "<p>x</p>"},
+		{name: "false start", writes: []string{"<p>a</bo", "ld></p>"}, passed: "<p>a</bold></p>"},
+		{name: "last of two", writes: []string{"<p>'</body>'</p>", "</body></html>"}, passed: "<p>'</body>'</p>"},
+		{name: "no body", writes: []string{"<p>a</p>", "<p>b</p>"}, passed: "<p>a</p><p>b</p>"},
+		{name: "no body, trailing prefix", writes: []string{"<p>a</p></b"}, passed: "<p>a</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := &bodyTailWriter{w: &out}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if got := out.String(); got != tt.passed {
+				t.Errorf("before tail = %q, want %q", got, tt.passed)
+			}
+			if err := w.writeTail(); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := out.String(), strings.Join(tt.writes, ""); got != want {
+				t.Errorf("after tail = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// slotLayout is a layout around a page with two slot placeholders.
+func slotLayout(base string) *MainLayoutPage {
+	fsys := fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(base)},
+		"pages/home.html":      {Data: []byte(`<div id="fast">…</div><div id="slow">…</div>`)},
+		"partials/value.html":  {Data: []byte(`[{{.}}]`)},
+		"partials/custom.html": {Data: []byte(`custom {{.ID}} {{.TimedOut}}`)},
+	}
+	loader := NewTemplateLoaderFS(TemplateLayer{Name: "test", FS: fsys})
+	loader.SetMode(ModeProduction)
+	return NewMainLayoutPageWithLoader("base.html", loader)
+}
+
+func value(v string, wait <-chan struct{}) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		if wait != nil {
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return v, nil
+	}
+}
+
+func TestSlots(t *testing.T) {
+	const body = `<html><body><main>{{.Content}}</main></body></html>`
+	block := make(chan struct{}) // never closed: the slot only ends by timeout
+	tests := []struct {
+		name    string
+		base    string
+		headers map[string]string
+		slots   func() []Slot
+		order   []string // substrings in output order
+		absent  []string
+	}{
+		{
+			name: "completion order, before </body>",
+			base: body,
+			slots: func() []Slot {
+				fastDone := make(chan struct{})
+				return []Slot{
+					{ID: "slow", Template: "partials/value", Load: func(ctx context.Context) (any, error) {
+						<-fastDone
+						time.Sleep(20 * time.Millisecond)
+						return "slow", nil
+					}},
+					{ID: "fast", Template: "partials/value", Load: func(ctx context.Context) (any, error) {
+						defer close(fastDone)
+						return "fast", nil
+					}},
+				}
+			},
+			order: []string{`<main>`, `window.lsSwapSlot =`, `<template id="ls-slot-fast">[fast]</template>`, `<template id="ls-slot-slow">[slow]</template>`, `</body></html>`},
+		},
+		{
+			name: "timeout uses the fallback",
+			base: body,
+			slots: func() []Slot {
+				return []Slot{{ID: "slow", Template: "partials/value", Load: value("x", block), Timeout: 10 * time.Millisecond}}
+			},
+			order: []string{`<template id="ls-slot-slow">`, `taking too long`, `</template>`, `</body>`},
+		},
+		{
+			name: "load error with custom fallback",
+			base: body,
+			slots: func() []Slot {
+				return []Slot{{ID: "fast", Template: "partials/value", Fallback: "partials/custom", Load: func(context.Context) (any, error) {
+					return nil, errors.New("boom")
+				}}}
+			},
+			order: []string{`<template id="ls-slot-fast">custom fast false</template>`, `</body>`},
+		},
+		{
+			name: "layout without </body>",
+			base: `<main>{{.Content}}</main>`,
+			slots: func() []Slot {
+				return []Slot{{ID: "fast", Template: "partials/value", Load: value("fast", nil)}}
+			},
+			order: []string{`</main>`, `<template id="ls-slot-fast">[fast]</template>`},
+		},
+		{
+			name:    "htmx partial streams out-of-band",
+			base:    body,
+			headers: map[string]string{"HX-Request": "true", "HX-Target": "pageContent"},
+			slots: func() []Slot {
+				return []Slot{{ID: "fast", Template: "partials/value", Load: value("fast", nil)}}
+			},
+			order:  []string{`<div id="fast">…</div>`, `<div id="fast" hx-swap-oob="innerHTML">[fast]</div>`},
+			absent: []string{`lsSwapSlot`, `<template`, `<main>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := slotLayout(tt.base)
+			c, w := newTestContext("/", tt.headers)
+			if err := m.WritePage(c, "home", nil, &PageOptions{Slots: tt.slots()}); err != nil {
+				t.Fatal(err)
+			}
+			body := w.Body.String()
+			rest := body
+			for _, s := range tt.order {
+				i := strings.Index(rest, s)
+				if i < 0 {
+					t.Fatalf("missing %q in order:\n%s", s, body)
+				}
+				rest = rest[i+len(s):]
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(body, s) {
+					t.Errorf("has %q:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
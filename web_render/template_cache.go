@@ -0,0 +1,101 @@
+package web_render
+
+import (
+	"html/template"
+	"io/fs"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TemplateMode controls how TemplateLoader caches parsed templates.
+type TemplateMode int
+
+const (
+	// ModeDevelopment re-parses a cached template when one of its source
+	// files changes on disk, or a file is added to or removed from a dir it
+	// was looked up in (checked by mtime on every lookup).
+	ModeDevelopment TemplateMode = iota
+	// ModeProduction parses each template once and never checks the disk again.
+	// Use Precompile at startup to fail fast on parse errors.
+	ModeProduction
+)
+
+// sourceFile is a template file a cached entry was parsed from, or a dir
+// its templates were looked up in.
+type sourceFile struct {
+	fsys    fs.FS
+	path    string
+	modTime time.Time
+	missing bool // the dir did not exist; creating it makes the entry stale
+}
+
+// cachedTemplate is a parsed template set with the files it was built from.
+type cachedTemplate struct {
+	tmpl    *template.Template
+	files   []sourceFile
+	dirs    []sourceFile // dev mode only, see TemplateLoader.sourceDirs
+	layouts []string     // layout chain for page sets, innermost first
+
+	assets   map[string]bool // script and stylesheet URLs the sources load literally
+	headHook bool            // a source places {{.HeadAssets}}
+	bodyHook bool            // a source places {{.BodyAssets}}
+}
+
+// stale reports whether any source file changed since the entry was parsed,
+// or any dir it was looked up in gained or lost a file. Files and dirs
+// without a modification time (e.g. embed.FS) never go stale.
+func (e *cachedTemplate) stale() bool {
+	for _, f := range slices.Concat(e.files, e.dirs) {
+		if f.modTime.IsZero() && !f.missing {
+			continue
+		}
+		info, err := fs.Stat(f.fsys, f.path)
+		if f.missing {
+			if err == nil {
+				return true
+			}
+			continue
+		}
+		if err != nil || !info.ModTime().Equal(f.modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateCache stores parsed templates by key, safe for concurrent use.
+// Parsed *template.Template values are only executed after they are cached,
+// which html/template allows from multiple goroutines.
+type templateCache struct {
+	mu      sync.RWMutex
+	entries map[string]*cachedTemplate
+}
+
+func (c *templateCache) get(key string) (*cachedTemplate, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+func (c *templateCache) put(key string, e *cachedTemplate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedTemplate)
+	}
+	c.entries[key] = e
+}
+
+func (c *templateCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func (c *templateCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
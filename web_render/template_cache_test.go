@@ -0,0 +1,165 @@
+package web_render
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingFS counts the calls that reach the disk.
+type countingFS struct {
+	fs.FS
+	calls atomic.Int64
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.calls.Add(1)
+	return c.FS.Open(name)
+}
+
+// writeTemplate writes a template file under dir with an mtime of at,
+// so edits within one clock tick still change it.
+func writeTemplate(t *testing.T, dir, name, src string, at time.Time) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cacheLoader is a loader on a temp dir with a layout and a users page.
+func cacheLoader(t *testing.T, mode TemplateMode) (*TemplateLoader, *countingFS, string) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeTemplate(t, dir, "layouts/base.html", `<main>{{.Content}}</main>`, start)
+	writeTemplate(t, dir, "pages/users.html", `users {{template "partials/x" .}}`, start)
+	writeTemplate(t, dir, "partials/row.html", `row`, start)
+	cfs := &countingFS{FS: os.DirFS(dir)}
+	loader := NewTemplateLoaderFS(TemplateLayer{Name: "project", FS: cfs})
+	loader.Framework = nil
+	loader.SetMode(mode)
+	return loader, cfs, dir
+}
+
+func executePage(t *testing.T, l *TemplateLoader) (string, error) {
+	t.Helper()
+	tmpl, err := l.LoadPage("base.html", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	err = tmpl.ExecuteTemplate(&b, "users.html", nil)
+	return b.String(), err
+}
+
+func TestTemplateCacheDevelopment(t *testing.T) {
+	l, _, dir := cacheLoader(t, ModeDevelopment)
+	if _, err := executePage(t, l); err == nil || !strings.Contains(err.Error(), "no such template") {
+		t.Fatalf("missing partial: err = %v", err)
+	}
+
+	writeTemplate(t, dir, "partials/x.html", `x1`, time.Now())
+	if out, err := executePage(t, l); err != nil || out != "users x1" {
+		t.Fatalf("added partial: %q, %v", out, err)
+	}
+
+	writeTemplate(t, dir, "partials/x.html", `x2`, time.Now().Add(time.Second))
+	if out, err := executePage(t, l); err != nil || out != "users x2" {
+		t.Fatalf("edited partial: %q, %v", out, err)
+	}
+
+	writeTemplate(t, dir, "partials/nested/y.html", `y`, time.Now())
+	tmpl, err := l.LoadPage("base.html", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Lookup("partials/nested/y") == nil {
+		t.Fatal("partial added in a new dir not loaded")
+	}
+}
+
+func TestTemplateCacheOverride(t *testing.T) {
+	framework := t.TempDir()
+	project := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeTemplate(t, framework, "layouts/base.html", `framework {{.Content}}`, start)
+	writeTemplate(t, framework, "pages/users.html", `users`, start)
+	l := NewTemplateLoaderFS(
+		TemplateLayer{Name: "project", FS: os.DirFS(project)},
+		TemplateLayer{Name: "framework", FS: os.DirFS(framework)},
+	)
+	l.Framework = nil
+	if _, err := l.LoadPage("base.html", "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, project, "layouts/base.html", `project {{.Content}}`, time.Now())
+	tmpl, err := l.LoadPage("base.html", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "base.html", LayoutData{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "project") {
+		t.Fatalf("override not picked up: %q", b.String())
+	}
+}
+
+func TestTemplateCacheProductionNeverStats(t *testing.T) {
+	l, cfs, dir := cacheLoader(t, ModeProduction)
+	writeTemplate(t, dir, "partials/x.html", `x1`, time.Now())
+	if _, err := executePage(t, l); err != nil {
+		t.Fatal(err)
+	}
+	cfs.calls.Store(0)
+	writeTemplate(t, dir, "partials/x.html", `x2`, time.Now().Add(time.Second))
+	out, err := executePage(t, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := cfs.calls.Load(); n != 0 {
+		t.Errorf("production lookup touched the disk %d times", n)
+	}
+	if out != "users x1" {
+		t.Errorf("production served %q, want the cached users x1", out)
+	}
+}
+
+func TestTemplateCacheConcurrentLoadPage(t *testing.T) {
+	for _, mode := range []TemplateMode{ModeDevelopment, ModeProduction} {
+		l, _, dir := cacheLoader(t, mode)
+		writeTemplate(t, dir, "partials/x.html", `x`, time.Now())
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					tmpl, err := l.LoadPage("base.html", "users")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if err := tmpl.ExecuteTemplate(&strings.Builder{}, "users.html", nil); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
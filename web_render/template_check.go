@@ -0,0 +1,293 @@
+package web_render
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// CheckIssue is one problem found by Check.
+type CheckIssue struct {
+	Layer    string // layer the file came from, e.g. "project"
+	File     string // path inside the layer
+	Line     int
+	Severity string // "error" or "warning"
+	Message  string
+}
+
+func (i CheckIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Severity, i.Message)
+}
+
+// CheckReport is the result of Check.
+type CheckReport struct {
+	Templates int // number of template files checked
+	Issues    []CheckIssue
+}
+
+// Errors returns the number of error-level issues.
+func (r *CheckReport) Errors() int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+// Warnings returns the number of warning-level issues.
+func (r *CheckReport) Warnings() int {
+	return len(r.Issues) - r.Errors()
+}
+
+func (r *CheckReport) add(src templateSource, line int, severity, format string, v ...any) {
+	r.Issues = append(r.Issues, CheckIssue{
+		Layer:    src.Layer,
+		File:     src.Path,
+		Line:     line,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
+
+var (
+	parseErrLineRe = regexp.MustCompile(`^template: [^:]+:(\d+):\s*(.*)$`)
+	actionRe       = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	customTagRe    = regexp.MustCompile(`(?s)<(ls-[a-z0-9-]+)(\s[^>]*)?>`)
+	tagAttrRe      = regexp.MustCompile(`([^\s=/>"']+)(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>]+))?`)
+)
+
+// Check statically verifies the template tree the loader would serve:
+// every layout, page and partial parses (errors reported as file:line),
+// every {{template "x"}} reference and declared layout resolves, and
+// every <ls-*> element and its attributes exist in components. Pass a nil
+// registry to skip the component checks. Unknown attributes are warnings;
+// everything else is an error.
+//
+// A page's references must resolve within its own template set: the page,
+// its declared layout chain (every layout when it declares none, as the
+// main layout is not known here), sidebar.html and the partials. Layouts,
+// the sidebar and partials render with any page, so theirs may resolve
+// anywhere in the tree.
+func (l *TemplateLoader) Check(components ComponentRegistry) *CheckReport {
+	report := &CheckReport{}
+	names, pageNames := l.templateNames()
+	isPage := map[string]bool{}
+	for _, p := range pageNames {
+		isPage[p+".html"] = true
+	}
+	var sources []templateSource
+	byName := map[string]templateSource{}
+	for _, name := range names {
+		src, err := l.find(name, nil)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, src)
+		byName[name] = src
+	}
+	partials := l.partials(nil)
+	sources = append(sources, partials...)
+	report.Templates = len(sources)
+
+	// Parse each file on its own so one broken file doesn't hide the others
+	funcs := l.funcMap()
+	defined := map[string]bool{}
+	defs := map[string][]string{} // names defined per file
+	trees := map[string][]*parse.Tree{}
+	for _, src := range sources {
+		t, err := template.New(src.Name).Funcs(funcs).Parse(string(src.content))
+		if err != nil {
+			line, msg := 0, err.Error()
+			if m := parseErrLineRe.FindStringSubmatch(msg); m != nil {
+				line, _ = strconv.Atoi(m[1])
+				msg = m[2]
+			}
+			report.add(src, line, "error", "parse: %s", msg)
+			continue
+		}
+		for _, tt := range t.Templates() {
+			defined[tt.Name()] = true
+			defs[src.Path+"|"+src.Layer] = append(defs[src.Path+"|"+src.Layer], tt.Name())
+			if tt.Tree != nil {
+				trees[src.Path+"|"+src.Layer] = append(trees[src.Path+"|"+src.Layer], tt.Tree)
+			}
+		}
+	}
+
+	// Defined in every page set: partials and the sidebar
+	shared := map[string]bool{}
+	for _, src := range partials {
+		for _, name := range defs[src.Path+"|"+src.Layer] {
+			shared[name] = true
+		}
+	}
+	if src, ok := byName["sidebar.html"]; ok {
+		for _, name := range defs[src.Path+"|"+src.Layer] {
+			shared[name] = true
+		}
+	}
+
+	for _, src := range sources {
+		visible := defined
+		if isPage[src.Name] && src.Name != "sidebar.html" && byName[src.Name].Path == src.Path {
+			visible = l.pageSetDefs(src, byName, isPage, defs, shared)
+		}
+		for _, tree := range trees[src.Path+"|"+src.Layer] {
+			for _, ref := range templateRefs(tree.Root) {
+				switch {
+				case visible[ref.Name]:
+				case defined[ref.Name]:
+					report.add(src, lineOf(tree, ref), "error", "{{template %q}} is defined only outside this page's template set (page, layout chain, sidebar, partials)", ref.Name)
+				default:
+					report.add(src, lineOf(tree, ref), "error", "{{template %q}} does not resolve to any layout, page, partial or define", ref.Name)
+				}
+			}
+		}
+		if src.layout != "" && src.layout != NoLayout {
+			if _, err := l.find(src.layout, nil); err != nil {
+				report.add(src, 1, "error", "declared layout %q not found", src.layout)
+			}
+		}
+		if components != nil {
+			checkComponents(report, src, components)
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report
+}
+
+// pageSetDefs returns the names defined in the template set page renders
+// with; see Check.
+func (l *TemplateLoader) pageSetDefs(page templateSource, byName map[string]templateSource, isPage map[string]bool, defs map[string][]string, shared map[string]bool) map[string]bool {
+	visible := map[string]bool{}
+	for name := range shared {
+		visible[name] = true
+	}
+	add := func(src templateSource) {
+		for _, name := range defs[src.Path+"|"+src.Layer] {
+			visible[name] = true
+		}
+	}
+	add(page)
+	if page.layout == "" || page.layout == NoLayout {
+		if page.layout == "" {
+			for name, src := range byName {
+				if !isPage[name] {
+					add(src)
+				}
+			}
+		}
+		return visible
+	}
+	seen := map[string]bool{}
+	for name := page.layout; name != "" && name != NoLayout && !seen[name]; {
+		seen[name] = true
+		src, ok := byName[name]
+		if !ok {
+			break // reported as a missing layout
+		}
+		add(src)
+		name = src.layout
+	}
+	return visible
+}
+
+// templateRefs collects every {{template}} call below node.
+func templateRefs(node parse.Node) []*parse.TemplateNode {
+	var refs []*parse.TemplateNode
+	var walk func(parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.TemplateNode:
+			refs = append(refs, n)
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+	return refs
+}
+
+func lineOf(tree *parse.Tree, node parse.Node) int {
+	loc, _ := tree.ErrorContext(node)
+	// loc is "name:line:col"
+	parts := strings.Split(loc, ":")
+	if len(parts) >= 3 {
+		line, _ := strconv.Atoi(parts[len(parts)-2])
+		return line
+	}
+	return 0
+}
+
+// checkComponents flags unknown <ls-*> elements and attributes in src.
+func checkComponents(report *CheckReport, src templateSource, components ComponentRegistry) {
+	// Blank out template actions, keeping newlines so offsets map to lines
+	html := actionRe.ReplaceAllStringFunc(string(src.content), func(a string) string {
+		return strings.Repeat("\n", strings.Count(a, "\n")) + strings.Repeat(" ", len(a)-strings.Count(a, "\n"))
+	})
+	for _, m := range customTagRe.FindAllStringSubmatchIndex(html, -1) {
+		line := strings.Count(html[:m[0]], "\n") + 1
+		tag := html[m[2]:m[3]]
+		if _, ok := components[tag]; !ok {
+			report.add(src, line, "error", "unknown element <%s>", tag)
+			continue
+		}
+		if m[4] < 0 {
+			continue
+		}
+		attrs := html[m[4]:m[5]]
+		for _, a := range tagAttrRe.FindAllStringSubmatchIndex(attrs, -1) {
+			name := attrs[a[2]:a[3]]
+			if !components.AllowsAttribute(tag, name) {
+				attrLine := line + strings.Count(attrs[:a[0]], "\n")
+				report.add(src, attrLine, "warning", "<%s> has no attribute %q", tag, name)
+			}
+		}
+	}
+}
+
+// CheckTemplates runs Check over the templates in dir (layouts/, pages/,
+// partials/) against the components in componentsDir; pass "" to skip
+// component checks. Handy from a project test:
+//
+//	report, err := web_render.CheckTemplates("../../templates", "../../components")
+//	if err != nil || report.Errors() > 0 { t.Fatal(report.Issues) }
+func CheckTemplates(dir, componentsDir string) (*CheckReport, error) {
+	loader := NewTemplateLoader(dir)
+	var components ComponentRegistry
+	if componentsDir != "" {
+		var err error
+		if components, err = LoadComponentRegistry(os.DirFS(componentsDir)); err != nil {
+			return nil, err
+		}
+	}
+	return loader.Check(components), nil
+}
@@ -0,0 +1,18 @@
+package web_render
+
+import "testing"
+
+// TestProjectTemplates runs the template checker over the repo templates
+// and web components, as a project would in its own tests.
+func TestProjectTemplates(t *testing.T) {
+	report, err := CheckTemplates("../templates", "../components")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Templates == 0 {
+		t.Fatal("no templates checked")
+	}
+	for _, issue := range report.Issues {
+		t.Error(issue)
+	}
+}
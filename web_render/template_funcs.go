@@ -0,0 +1,236 @@
+package web_render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeFormat is used by formatTime when no layout is given.
+const DefaultTimeFormat = "Jan 2, 3:04 PM"
+
+// Funcs registers template functions applied to every template the loader
+// parses, overriding built-ins with the same name. Register functions at
+// startup, before the first render; cached templates are dropped.
+func (l *TemplateLoader) Funcs(funcs template.FuncMap) *TemplateLoader {
+	l.funcsMu.Lock()
+	if l.funcs == nil {
+		l.funcs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		l.funcs[name] = fn
+	}
+	l.funcsMu.Unlock()
+	l.cache.reset()
+	return l
+}
+
+// funcMap returns the built-ins merged with registered functions.
+func (l *TemplateLoader) funcMap() template.FuncMap {
+	fm := l.builtinFuncs()
+	l.funcsMu.RLock()
+	defer l.funcsMu.RUnlock()
+	for name, fn := range l.funcs {
+		fm[name] = fn
+	}
+	return fm
+}
+
+// builtinFuncs is the helper library available in every template:
+//
+//	dict "key" value ...       map for passing several values to {{template}}
+//	list a b c                 []any
+//	json value                 JSON, entity-escaped in attributes, raw value in <script>
+//	formatTime "layout" t      t.Format(layout); "" uses DefaultTimeFormat
+//	timeAgo t                  "5 minutes ago"
+//	humanizeNumber n           "3,247", "1,234.5"
+//	default fallback value     value, or fallback when value is empty
+//	safeHTML s                 s as trusted HTML (never pass user input)
+//	asset "css/app.css"        AssetPrefix + path, with ?v=AssetVersion
+//	icon "name" ["size"]       <ls-icon> element
+func (l *TemplateLoader) builtinFuncs() template.FuncMap {
+	return template.FuncMap{
+		"dict":           dict,
+		"list":           list,
+		"json":           toJSON,
+		"formatTime":     formatTime,
+		"timeAgo":        timeAgo,
+		"humanizeNumber": humanizeNumber,
+		"default":        defaultValue,
+		"safeHTML":       safeHTML,
+		"asset":          l.assetURL,
+		"icon":           icon,
+	}
+}
+
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+func list(items ...any) []any {
+	return items
+}
+
+// toJSON returns template.JS: html/template entity-encodes it inside regular
+// attributes (menu-items='{{json .Menu}}') and emits it as a JS value inside
+// <script>. json.Marshal already escapes <, > and &.
+func toJSON(v any) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func formatTime(layout string, v any) string {
+	t, ok := toTime(v)
+	if !ok || t.IsZero() {
+		return ""
+	}
+	if layout == "" {
+		layout = DefaultTimeFormat
+	}
+	return t.Format(layout)
+}
+
+func timeAgo(v any) string {
+	t, ok := toTime(v)
+	if !ok || t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s %s", unit, suffix)
+		}
+		return fmt.Sprintf("%d %ss %s", n, unit, suffix)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/24), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/(24*30)), "month")
+	default:
+		return plural(int(d.Hours()/(24*365)), "year")
+	}
+}
+
+func humanizeNumber(v any) (string, error) {
+	var s string
+	switch n := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s = fmt.Sprintf("%d", n)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return fmt.Sprint(n), nil
+		}
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return "", fmt.Errorf("humanizeNumber: unsupported type %T", v)
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + frac, nil
+}
+
+func defaultValue(fallback any, v any) any {
+	if v == nil {
+		return fallback
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() {
+		return fallback
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		if rv.Len() == 0 {
+			return fallback
+		}
+	}
+	return v
+}
+
+func safeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// assetURL builds a static asset URL under AssetPrefix, with AssetVersion
+// appended for cache busting. Absolute URLs are returned unchanged.
+func (l *TemplateLoader) assetURL(p string) string {
+	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	prefix := l.AssetPrefix
+	if prefix == "" {
+		prefix = "/static"
+	}
+	url := strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(p, "/")
+	if l.AssetVersion != "" {
+		url += "?v=" + l.AssetVersion
+	}
+	return url
+}
+
+func icon(name string, size ...string) template.HTML {
+	attrs := `name="` + template.HTMLEscapeString(name) + `"`
+	if len(size) > 0 && size[0] != "" {
+		attrs += ` size="` + template.HTMLEscapeString(size[0]) + `"`
+	}
+	return template.HTML("<ls-icon " + attrs + "></ls-icon>")
+}
@@ -4,124 +4,540 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-// TemplateLoader loads templates from a directory or embed.FS
+// TemplateLoader loads templates from an ordered list of fs.FS layers.
 // Usage: loader := NewTemplateLoader("./templates")
 //
 //	tmpl := loader.Load("base.html")
 //
-// TemplateLoader handles project-level asset loading and override logic.
-// Usage: loader := NewTemplateLoader("./templates")
+// Layers are searched in order, so a project layer listed first overrides a
+// theme layer, which in turn overrides a framework layer:
+//
+//	loader := NewTemplateLoaderFS(
+//		TemplateLayer{Name: "project", FS: os.DirFS("templates")},
+//		TemplateLayer{Name: "theme", FS: themeFS},
+//	)
 type TemplateLoader struct {
-	LayoutDir string    // path to layouts (project)
-	PageDir   string    // path to pages (project)
-	EmbedFS   *embed.FS // optional, for embedded fallback
+	LayoutDir  string          // layouts dir inside each layer (default "layouts")
+	PageDir    string          // pages dir inside each layer (default "pages")
+	PartialDir string          // partials dir inside each layer (default "partials")
+	Layers     []TemplateLayer // searched in order, first match wins
+	Mode       TemplateMode    // cache behavior, default ModeDevelopment
+
+	// Framework is the last-resort layer (default layouts, sidebar, error
+	// pages). Set to nil to disable framework fallback.
+	Framework *FrameworkAssetLoader
+
+	Logger Logger // optional, e.g. lokstra.Logger or NewSlogLogger(...); nil discards
+	Trace  bool   // record a RenderTrace for every RenderPage call
+
+	AssetPrefix  string // URL prefix for the asset func, default "/static"
+	AssetVersion string // appended as ?v= by the asset func, for cache busting
+
+	cache   templateCache
+	funcs   template.FuncMap
+	funcsMu sync.RWMutex
+}
+
+// TemplateLayer is one source of templates for a TemplateLoader.
+// Any fs.FS works: os.DirFS, embed.FS, fstest.MapFS, zip.Reader, ...
+type TemplateLayer struct {
+	Name       string // layer name reported by Resolve, e.g. "project", "theme"
+	FS         fs.FS
+	LayoutDir  string // overrides TemplateLoader.LayoutDir for this layer
+	PageDir    string // overrides TemplateLoader.PageDir for this layer
+	PartialDir string // overrides TemplateLoader.PartialDir for this layer
+}
+
+// Resolution describes where a template name was found.
+type Resolution struct {
+	Name  string // requested template name
+	Layer string // name of the layer that resolved it
+	Path  string // path inside the layer FS
 }
 
 // FrameworkAssetLoader handles framework-level asset loading only.
+// TemplateLoader consults it after all of its layers, so projects override
+// framework layouts simply by providing a file with the same name.
 type FrameworkAssetLoader struct {
-	LayoutDir string    // path to layouts (framework)
-	PageDir   string    // path to pages (framework)
-	JsDir     string    // path to js (framework)
-	CssDir    string    // path to css (framework)
-	EmbedFS   *embed.FS // optional, for embedded fallback
+	LayoutDir  string // path to layouts inside FS (framework)
+	PageDir    string // path to pages inside FS (framework)
+	PartialDir string // path to partials inside FS (framework)
+	JsDir      string // path to js inside FS (framework)
+	CssDir     string // path to css inside FS (framework)
+	FS         fs.FS  // root of framework assets (os.DirFS or embed.FS)
 }
 
-// Create a new TemplateLoader for project assets.
+// Create a new TemplateLoader for project assets in dir.
 func NewTemplateLoader(dir string) *TemplateLoader {
+	return NewTemplateLoaderFS(TemplateLayer{Name: "project", FS: os.DirFS(dir)})
+}
+
+// NewTemplateLoaderFS creates a TemplateLoader over the given layers,
+// highest priority first.
+func NewTemplateLoaderFS(layers ...TemplateLayer) *TemplateLoader {
 	return &TemplateLoader{
-		LayoutDir: dir + "/layouts",
-		PageDir:   dir + "/pages",
-		EmbedFS:   nil,
+		LayoutDir:  "layouts",
+		PageDir:    "pages",
+		PartialDir: "partials",
+		Layers:     layers,
+		Framework:  DefaultFrameworkAssetLoader(),
 	}
 }
 
-// Create a new FrameworkAssetLoader for framework assets.
+// Create a new FrameworkAssetLoader for framework assets in dir.
 func NewFrameworkAssetLoader(dir string) *FrameworkAssetLoader {
+	return NewFrameworkAssetLoaderFS(os.DirFS(dir))
+}
+
+// NewFrameworkAssetLoaderFS creates a FrameworkAssetLoader rooted at fsys.
+func NewFrameworkAssetLoaderFS(fsys fs.FS) *FrameworkAssetLoader {
 	return &FrameworkAssetLoader{
-		LayoutDir: dir + "/layouts",
-		PageDir:   dir + "/layouts", // fallback pages to layouts
-		JsDir:     dir + "/static/js",
-		CssDir:    dir + "/static/css",
-		EmbedFS:   nil,
+		LayoutDir:  "layouts",
+		PageDir:    "layouts", // fallback pages to layouts
+		PartialDir: "partials",
+		JsDir:      "static/js",
+		CssDir:     "static/css",
+		FS:         fsys,
 	}
 }
 
 // NewTemplateLoaderWithEmbed: loader dengan embed fallback
-// Create a new TemplateLoader with embedded fallback.
-func NewTemplateLoaderWithEmbed(dir string, fs *embed.FS) *TemplateLoader {
-	return &TemplateLoader{
-		LayoutDir: dir + "/layouts",
-		PageDir:   dir + "/pages",
-		EmbedFS:   fs,
+// Create a new TemplateLoader with embedded fallback: dir on disk first,
+// then dir inside the embed.FS, then the embed.FS root.
+func NewTemplateLoaderWithEmbed(dir string, efs *embed.FS) *TemplateLoader {
+	l := NewTemplateLoader(dir)
+	if efs != nil {
+		if sub, err := fs.Sub(efs, path.Clean(dir)); err == nil {
+			l.AddLayer("embed", sub)
+		}
+		l.Layers = append(l.Layers, TemplateLayer{Name: "embed-root", FS: efs, LayoutDir: ".", PageDir: "."})
 	}
+	return l
 }
 
-// Create a new FrameworkAssetLoader with embedded fallback.
-func NewFrameworkAssetLoaderWithEmbed(dir string, fs *embed.FS) *FrameworkAssetLoader {
-	return &FrameworkAssetLoader{
-		LayoutDir: dir + "/layouts",
-		PageDir:   dir + "/layouts",
-		JsDir:     dir + "/static/js",
-		CssDir:    dir + "/static/css",
-		EmbedFS:   fs,
+// Create a new FrameworkAssetLoader from dir inside an embed.FS.
+func NewFrameworkAssetLoaderWithEmbed(dir string, efs *embed.FS) *FrameworkAssetLoader {
+	sub, err := fs.Sub(efs, path.Clean(dir))
+	if err != nil {
+		return NewFrameworkAssetLoaderFS(efs)
+	}
+	return NewFrameworkAssetLoaderFS(sub)
+}
+
+// Layer exposes the framework templates as a TemplateLayer.
+func (f *FrameworkAssetLoader) Layer() TemplateLayer {
+	return TemplateLayer{
+		Name:       "framework",
+		FS:         f.FS,
+		LayoutDir:  f.LayoutDir,
+		PageDir:    f.PageDir,
+		PartialDir: f.PartialDir,
+	}
+}
+
+// AddLayer appends a layer with lower priority than the existing ones.
+func (l *TemplateLoader) AddLayer(name string, fsys fs.FS) {
+	l.Layers = append(l.Layers, TemplateLayer{Name: name, FS: fsys})
+	l.cache.reset()
+}
+
+// PrependLayer adds a layer that overrides all existing ones.
+func (l *TemplateLoader) PrependLayer(name string, fsys fs.FS) {
+	l.Layers = append([]TemplateLayer{{Name: name, FS: fsys}}, l.Layers...)
+	l.cache.reset()
+}
+
+// SetMode switches the cache mode and drops every cached template.
+func (l *TemplateLoader) SetMode(mode TemplateMode) {
+	l.Mode = mode
+	l.cache.reset()
+}
+
+// Invalidate drops every cached template; the next lookup re-parses from source.
+func (l *TemplateLoader) Invalidate() {
+	l.cache.reset()
+}
+
+func (l *TemplateLoader) logger() Logger {
+	if l.Logger == nil {
+		return nopLogger{}
+	}
+	return l.Logger
+}
+
+// Resolve reports which layer and path a template name resolves to,
+// without parsing it.
+func (l *TemplateLoader) Resolve(name string) (Resolution, error) {
+	src, err := l.find(name, nil)
+	if err != nil {
+		return Resolution{}, err
 	}
+	return src.Resolution, nil
 }
 
-// Load template from project assets only (layouts/pages). No framework fallback.
+// Load returns the parsed template for name, from cache when possible.
+// The template is registered under name, so callers execute it with
+// tmpl.ExecuteTemplate(w, name, data). All partials are included.
 func (l *TemplateLoader) Load(name string) (*template.Template, error) {
-	projectRoot, _ := os.Getwd()
-
-	// 1. Check layout path Project
-	layoutPath := filepath.Join(projectRoot, l.LayoutDir, name)
-	fmt.Printf("[DEBUG] Trying layout path: %s\n", layoutPath)
-	if _, err := os.Stat(layoutPath); err == nil {
-		fmt.Printf("[DEBUG] Found template at layout path: %s\n", layoutPath)
-		return template.ParseFiles(layoutPath)
-	} else {
-		fmt.Printf("[DEBUG] Layout path not found: %v\n", err)
-	}
-
-	// 2. Check page path Project
-	pagePath := filepath.Join(projectRoot, l.PageDir, name)
-	fmt.Printf("[DEBUG] Trying page path: %s\n", pagePath)
-	if _, err := os.Stat(pagePath); err == nil {
-		fmt.Printf("[DEBUG] Found template at page path: %s\n", pagePath)
-		return template.ParseFiles(pagePath)
-	} else {
-		fmt.Printf("[DEBUG] Page path not found: %v\n", err)
-	}
-	if l.EmbedFS != nil {
-		// 3. Check embed.FS layout dir fallback
-		fmt.Printf("[DEBUG] Trying embed.FS for layout: %s\n", filepath.Join(l.LayoutDir, name))
-		tmplBytes, err := l.EmbedFS.ReadFile(filepath.Join(l.LayoutDir, name))
-		if err == nil {
-			fmt.Printf("[DEBUG] Found template in embed.FS layout: %s\n", filepath.Join(l.LayoutDir, name))
-			return template.New(name).Parse(string(tmplBytes))
-		}
-		fmt.Printf("[DEBUG] Embed.FS layout not found: %v\n", err)
-
-		// 4. Check embed.FS page dir fallback
-		fmt.Printf("[DEBUG] Trying embed.FS for page: %s\n", filepath.Join(l.PageDir, name))
-		tmplBytes, err = l.EmbedFS.ReadFile(filepath.Join(l.PageDir, name))
-		if err == nil {
-			fmt.Printf("[DEBUG] Found template in embed.FS page: %s\n", filepath.Join(l.PageDir, name))
-			return template.New(name).Parse(string(tmplBytes))
-		}
-		fmt.Printf("[DEBUG] Embed.FS page not found: %v\n", err)
-
-		// 5. Check embed.FS root fallback
-		fmt.Printf("[DEBUG] Trying embed.FS for root: %s\n", name)
-		tmplBytes, err = l.EmbedFS.ReadFile(name)
-		if err == nil {
-			fmt.Printf("[DEBUG] Found template in embed.FS root: %s\n", name)
-			return template.New(name).Parse(string(tmplBytes))
-		}
-		fmt.Printf("[DEBUG] Embed.FS root not found: %v\n", err)
-	}
-	fmt.Printf("[ERROR] Template %s not found in project or embed.FS\n", name)
-	return nil, fmt.Errorf("template %s not found in project or embed.FS", name)
+	return l.load(name, nil)
+}
+
+func (l *TemplateLoader) load(name string, tr *RenderTrace) (*template.Template, error) {
+	e, err := l.cached(name, tr, func() (*cachedTemplate, error) {
+		src, err := l.find(name, tr)
+		if err != nil {
+			return nil, err
+		}
+		return l.parseSources(tr, append([]templateSource{src}, l.partials(tr)...)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.tmpl, nil
+}
+
+// LoadPage returns the page and its layout chain parsed into a single
+// template set, keyed by layout+page. layout is used unless the page
+// declares its own (see declaredLayout); each layout may in turn declare a
+// parent layout. The sidebar and every partial under PartialDir are added.
+// Template names are "<page>.html", each layout file name, "sidebar.html"
+// and "partials/<name>" (e.g. "partials/users-table").
+// An empty page loads the layout chain with its partials only.
+func (l *TemplateLoader) LoadPage(layout, page string) (*template.Template, error) {
+	e, err := l.loadPage(layout, page, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.tmpl, nil
+}
+
+// LayoutChain returns the layouts page renders inside, innermost first.
+func (l *TemplateLoader) LayoutChain(layout, page string) ([]string, error) {
+	e, err := l.loadPage(layout, page, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.layouts, nil
+}
+
+// loadPage builds the page set; force ignores the layout the page declares.
+func (l *TemplateLoader) loadPage(layout, page string, force bool, tr *RenderTrace) (*cachedTemplate, error) {
+	key := layout + "|" + page
+	if force {
+		key += "|force"
+	}
+	return l.cached(key, tr, func() (*cachedTemplate, error) {
+		var sources []templateSource
+		if page != "" {
+			pageSrc, err := l.find(page+".html", tr)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, pageSrc)
+			if !force && pageSrc.layout != "" {
+				layout = pageSrc.layout
+			}
+		}
+
+		// Walk up the layout chain: settings.html -> admin.html -> base.html
+		var chain []string
+		seen := map[string]bool{}
+		for name := layout; name != "" && name != NoLayout; {
+			if seen[name] {
+				return nil, fmt.Errorf("layout cycle: %s declares %s again", strings.Join(chain, " -> "), name)
+			}
+			seen[name] = true
+			src, err := l.find(name, tr)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, src)
+			chain = append(chain, name)
+			name = src.layout
+		}
+
+		if sidebarSrc, err := l.find("sidebar.html", tr); err == nil && !seen["sidebar.html"] {
+			sources = append(sources, sidebarSrc)
+		}
+		sources = append(sources, l.partials(tr)...)
+		e, err := l.parseSources(tr, sources...)
+		if err != nil {
+			return nil, err
+		}
+		e.layouts = chain
+		return e, nil
+	})
+}
+
+// Precompile parses every template under LayoutDir and PageDir of every
+// layer and, for each given layout, every layout+page combination. It
+// returns the first parse error, so production servers can fail fast at
+// startup.
+func (l *TemplateLoader) Precompile(layouts ...string) error {
+	names, pages := l.templateNames()
+	for _, name := range names {
+		if _, err := l.Load(name); err != nil {
+			l.logger().Errorf("precompile %s: %v", name, err)
+			return err
+		}
+	}
+	for _, layout := range layouts {
+		for _, page := range pages {
+			if _, err := l.LoadPage(layout, page); err != nil {
+				l.logger().Errorf("precompile %s with layout %s: %v", page, layout, err)
+				return err
+			}
+		}
+	}
+	l.logger().Infof("precompiled %d templates", l.cache.len())
+	return nil
+}
+
+// templateNames lists every .html template name visible through the
+// layers, and the page names (without extension) among them.
+func (l *TemplateLoader) templateNames() (names []string, pages []string) {
+	seen := map[string]bool{}
+	seenPage := map[string]bool{}
+	for _, layer := range l.allLayers() {
+		for _, dir := range []string{l.layoutDir(layer), l.pageDir(layer)} {
+			isPageDir := dir == l.pageDir(layer) && dir != l.layoutDir(layer)
+			_ = fs.WalkDir(layer.FS, dir, func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || path.Ext(p) != ".html" {
+					return nil
+				}
+				name := strings.TrimPrefix(p, dir+"/")
+				if dir == "." {
+					name = p
+				}
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+				if isPageDir && !seenPage[name] {
+					seenPage[name] = true
+					pages = append(pages, strings.TrimSuffix(name, ".html"))
+				}
+				return nil
+			})
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(pages)
+	return names, pages
+}
+
+// cached returns the entry for key, rebuilding it when missing or, in
+// ModeDevelopment, when one of its source files changed.
+func (l *TemplateLoader) cached(key string, tr *RenderTrace, build func() (*cachedTemplate, error)) (*cachedTemplate, error) {
+	if e, ok := l.cache.get(key); ok && (l.Mode == ModeProduction || !e.stale()) {
+		tr.add(TraceStep{Kind: "cache", Name: key})
+		return e, nil
+	}
+	e, err := build()
+	if err != nil {
+		return nil, err
+	}
+	l.cache.put(key, e)
+	return e, nil
+}
+
+// templateSource is a template file resolved by the loader.
+type templateSource struct {
+	Resolution
+	fsys    fs.FS
+	content []byte // source with any layout declaration stripped
+	layout  string // layout declared by the template, if any
+}
+
+// parseSources parses all sources into one template set, each under its
+// logical name, and records file mtimes for staleness checks.
+func (l *TemplateLoader) parseSources(tr *RenderTrace, sources ...templateSource) (*cachedTemplate, error) {
+	e := &cachedTemplate{assets: map[string]bool{}}
+	funcs := l.funcMap()
+	for _, src := range sources {
+		start := time.Now()
+		var t *template.Template
+		if e.tmpl == nil {
+			e.tmpl = template.New(src.Name).Funcs(funcs)
+			t = e.tmpl
+		} else {
+			t = e.tmpl.New(src.Name)
+		}
+		if _, err := t.Parse(string(src.content)); err != nil {
+			tr.add(TraceStep{Kind: "parse", Name: src.Name, Layer: src.Layer, Path: src.Path, Err: err.Error()})
+			l.logger().Errorf("parse template %s (%s layer, %s): %v", src.Name, src.Layer, src.Path, err)
+			te := newTemplateError(ErrTemplateParse, src.Name, err)
+			te.Name, te.Layer, te.Path = src.Name, src.Layer, src.Path
+			return nil, te
+		}
+		tr.add(TraceStep{Kind: "parse", Name: src.Name, Layer: src.Layer, Path: src.Path, Duration: time.Since(start)})
+		for _, u := range sourceAssets(src.content) {
+			e.assets[u] = true
+		}
+		if headAssetsRe.Match(src.content) {
+			e.headHook = true
+		}
+		if bodyAssetsRe.Match(src.content) {
+			e.bodyHook = true
+		}
+		f := sourceFile{fsys: src.fsys, path: src.Path}
+		if info, err := fs.Stat(src.fsys, src.Path); err == nil {
+			f.modTime = info.ModTime()
+		}
+		e.files = append(e.files, f)
+	}
+	if l.Mode == ModeDevelopment {
+		e.dirs = l.sourceDirs(sources)
+	}
+	return e, nil
+}
+
+// sourceDirs lists the dirs, in every layer, that a lookup of sources
+// searched or would search: the layout and page dirs the source names
+// reach into and every dir under the partial dir. A file added to one of
+// them, e.g. a new partial or a project file overriding the framework's,
+// changes its mtime.
+func (l *TemplateLoader) sourceDirs(sources []templateSource) []sourceFile {
+	var dirs []sourceFile
+	for _, layer := range l.allLayers() {
+		if layer.FS == nil {
+			continue
+		}
+		seen := map[string]bool{}
+		add := func(p string) {
+			if seen[p] {
+				return
+			}
+			seen[p] = true
+			f := sourceFile{fsys: layer.FS, path: p}
+			if info, err := fs.Stat(layer.FS, p); err == nil {
+				f.modTime = info.ModTime()
+			} else {
+				f.missing = true
+			}
+			dirs = append(dirs, f)
+		}
+		for _, src := range sources {
+			if strings.HasPrefix(src.Name, "partials/") {
+				continue
+			}
+			for _, dir := range []string{l.layoutDir(layer), l.pageDir(layer)} {
+				add(path.Join(dir, path.Dir(src.Name)))
+			}
+		}
+		if dir := l.partialDir(layer); dir != "" {
+			add(dir)
+			_ = fs.WalkDir(layer.FS, dir, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && d.IsDir() {
+					add(p)
+				}
+				return nil
+			})
+		}
+	}
+	return dirs
+}
+
+// allLayers returns the project layers followed by the framework layer.
+func (l *TemplateLoader) allLayers() []TemplateLayer {
+	if l.Framework == nil || l.Framework.FS == nil {
+		return l.Layers
+	}
+	return append(l.Layers[:len(l.Layers):len(l.Layers)], l.Framework.Layer())
+}
+
+// partials returns every .html file under PartialDir (nested dirs included)
+// across all layers, named "partials/<path without .html>". A partial in an
+// earlier layer overrides one with the same name in a later layer.
+func (l *TemplateLoader) partials(tr *RenderTrace) []templateSource {
+	var sources []templateSource
+	seen := map[string]bool{}
+	for _, layer := range l.allLayers() {
+		dir := l.partialDir(layer)
+		if layer.FS == nil || dir == "" {
+			continue
+		}
+		_ = fs.WalkDir(layer.FS, dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(p) != ".html" {
+				return nil
+			}
+			name := "partials/" + strings.TrimSuffix(strings.TrimPrefix(p, dir+"/"), ".html")
+			if seen[name] {
+				return nil
+			}
+			content, err := fs.ReadFile(layer.FS, p)
+			if err != nil {
+				l.logger().Warnf("read partial %s from %s layer: %v", p, layer.Name, err)
+				return nil
+			}
+			seen[name] = true
+			tr.add(TraceStep{Kind: "resolve", Name: name, Layer: layer.Name, Path: p})
+			sources = append(sources, templateSource{
+				Resolution: Resolution{Name: name, Layer: layer.Name, Path: p},
+				fsys:       layer.FS,
+				content:    content,
+			})
+			return nil
+		})
+	}
+	return sources
+}
+
+func (l *TemplateLoader) partialDir(layer TemplateLayer) string {
+	if layer.PartialDir != "" {
+		return layer.PartialDir
+	}
+	return l.PartialDir
+}
+
+func (l *TemplateLoader) layoutDir(layer TemplateLayer) string {
+	if layer.LayoutDir != "" {
+		return layer.LayoutDir
+	}
+	return l.LayoutDir
+}
+
+func (l *TemplateLoader) pageDir(layer TemplateLayer) string {
+	if layer.PageDir != "" {
+		return layer.PageDir
+	}
+	return l.PageDir
+}
+
+// find resolves name against each layer's layout dir, then page dir,
+// returning the first match. The framework layer is tried last.
+func (l *TemplateLoader) find(name string, tr *RenderTrace) (templateSource, error) {
+	for _, layer := range l.allLayers() {
+		if layer.FS == nil {
+			continue
+		}
+		for _, dir := range []string{l.layoutDir(layer), l.pageDir(layer)} {
+			p := path.Join(dir, name)
+			content, err := fs.ReadFile(layer.FS, p)
+			if err != nil {
+				tr.add(TraceStep{Kind: "try", Name: name, Layer: layer.Name, Path: p})
+				continue
+			}
+			tr.add(TraceStep{Kind: "resolve", Name: name, Layer: layer.Name, Path: p})
+			l.logger().Debugf("template %s resolved from %s layer: %s", name, layer.Name, p)
+			layout, content := declaredLayout(content)
+			return templateSource{
+				Resolution: Resolution{Name: name, Layer: layer.Name, Path: p},
+				fsys:       layer.FS,
+				content:    content,
+				layout:     layout,
+			}, nil
+		}
+	}
+	l.logger().Debugf("template %s not found in any layer or framework", name)
+	return templateSource{}, &TemplateError{Kind: ErrTemplateNotFound, Name: name}
 }
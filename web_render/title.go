@@ -0,0 +1,55 @@
+package web_render
+
+import (
+	"html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// DefaultTitleFormat formats document titles when MainLayoutPage.TitleFormat
+// is empty, and for PageHandler. It is a text/template executed with the
+// PageContent, e.g. "{{.Title}} - Lokstra Framework". Empty uses the page
+// title as is.
+var DefaultTitleFormat = ""
+
+var titleFormats sync.Map // format -> *texttemplate.Template
+
+// formatTitle applies format to the page title. Pages without a title get
+// an empty document title, so partial swaps of small fragments leave the
+// browser title alone.
+func formatTitle(format string, pc *PageContent, log Logger) string {
+	if pc.Title == "" {
+		return ""
+	}
+	if format == "" {
+		format = DefaultTitleFormat
+	}
+	if format == "" {
+		return pc.Title
+	}
+	t, ok := titleFormats.Load(format)
+	if !ok {
+		parsed, err := texttemplate.New("title").Parse(format)
+		if err != nil {
+			log.Errorf("title format %q: %v", format, err)
+			return pc.Title
+		}
+		t, _ = titleFormats.LoadOrStore(format, parsed)
+	}
+	var b strings.Builder
+	if err := t.(*texttemplate.Template).Execute(&b, pc); err != nil {
+		log.Errorf("title format %q: %v", format, err)
+		return pc.Title
+	}
+	return b.String()
+}
+
+// titleTag renders the <title> element htmx picks up from partial
+// responses to update the browser tab, and with it the history entry.
+func titleTag(title string) string {
+	if title == "" {
+		return ""
+	}
+	return "<title>" + template.HTMLEscapeString(title) + "</title>"
+}
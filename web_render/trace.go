@@ -0,0 +1,81 @@
+package web_render
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RenderTrace records how a render resolved, parsed and executed its
+// templates. It is only collected when TemplateLoader.Trace is enabled.
+type RenderTrace struct {
+	Template string        // requested page template
+	Layout   string        // layout used, empty for partial renders
+	Steps    []TraceStep   // resolution and execution trail, in order
+	Total    time.Duration // wall time of the whole render
+}
+
+// TraceStep is one entry of a RenderTrace.
+type TraceStep struct {
+	Kind     string        // "try", "resolve", "cache", "parse", "execute", "provider"
+	Name     string        // template name
+	Layer    string        // layer name, for "try" and "resolve"
+	Path     string        // path inside the layer FS
+	Duration time.Duration // for "parse" and "execute"
+	Err      string        // error text, if the step failed
+}
+
+// add appends a step; safe to call on a nil trace.
+func (t *RenderTrace) add(step TraceStep) {
+	if t != nil {
+		t.Steps = append(t.Steps, step)
+	}
+}
+
+// String renders the trace one step per line, for logs and debugging.
+func (t *RenderTrace) String() string {
+	if t == nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "render %s (layout %q) in %s\n", t.Template, t.Layout, t.Total)
+	for _, s := range t.Steps {
+		fmt.Fprintf(&b, "  %-8s %s", s.Kind, s.Name)
+		if s.Layer != "" {
+			fmt.Fprintf(&b, " [%s] %s", s.Layer, s.Path)
+		}
+		if s.Duration > 0 {
+			fmt.Fprintf(&b, " %s", s.Duration)
+		}
+		if s.Err != "" {
+			fmt.Fprintf(&b, " error: %s", s.Err)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type traceKey struct{}
+
+// WithRenderTrace returns a copy of ctx carrying t.
+func WithRenderTrace(ctx context.Context, t *RenderTrace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// RenderTraceFrom returns the trace attached to ctx, or nil.
+func RenderTraceFrom(ctx context.Context) *RenderTrace {
+	t, _ := ctx.Value(traceKey{}).(*RenderTrace)
+	return t
+}
+
+// RenderTraceFromRequest returns the trace RenderPage attached to r, or nil.
+// RenderPage replaces c.Request with a copy carrying the trace, so
+// middleware holding the request.Context can inspect it after the handler.
+func RenderTraceFromRequest(r *http.Request) *RenderTrace {
+	if r == nil {
+		return nil
+	}
+	return RenderTraceFrom(r.Context())
+}
@@ -0,0 +1,112 @@
+package web_render
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/primadi/lokstra/core/request"
+)
+
+// Page is a page template bound to its data type, declared once at startup:
+//
+//	var dashboardPage = web_render.MustPage[Dashboard](layout, "dashboard")
+//
+//	regCtx.RegisterHandler("page.dashboard", dashboardPage.Handler(loadDashboard, opts))
+//
+// NewPage dry-executes the page and its layout chain against a zero T (or
+// the given fixture), so a renamed field fails at startup instead of
+// producing a broken page in production. Only branches the fixture reaches
+// are checked; pass a fixture with populated fields for deeper coverage.
+// Nil pointers the execution runs into are not errors, since real data
+// fills them; missing fields and methods are.
+type Page[T any] struct {
+	Template string
+	Layout   *MainLayoutPage
+}
+
+// NewPage declares a typed page and verifies it against fixture, or the zero
+// value of T when no fixture is given, with its nested pointers allocated.
+func NewPage[T any](layout *MainLayoutPage, templateName string, fixture ...T) (*Page[T], error) {
+	var data T
+	if len(fixture) > 0 {
+		data = fixture[0]
+	} else {
+		v := reflect.ValueOf(&data).Elem()
+		allocPointers(v, map[reflect.Type]bool{})
+	}
+	p := &Page[T]{Template: templateName, Layout: layout}
+	if err := p.dryRun(data); err != nil {
+		return nil, fmt.Errorf("page %s (%T): %w", templateName, data, err)
+	}
+	return p, nil
+}
+
+// MustPage is like NewPage but panics on error, for package-level declarations.
+func MustPage[T any](layout *MainLayoutPage, templateName string, fixture ...T) *Page[T] {
+	p, err := NewPage(layout, templateName, fixture...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Render renders the page with typed data; see MainLayoutPage.RenderPage.
+func (p *Page[T]) Render(c *request.Context, data T, opts *PageOptions) (*PageContent, error) {
+	return p.Layout.RenderPage(c, p.Template, data, opts)
+}
+
+// Handler is MainLayoutPage.Handler with typed data.
+func (p *Page[T]) Handler(data func(c *request.Context, opts *PageOptions) (T, error), opts *PageOptions) request.HandlerFunc {
+	return p.Layout.Handler(p.Template, func(c *request.Context, opts *PageOptions) (any, error) {
+		return data(c, opts)
+	}, opts)
+}
+
+// dryRun executes the page and every layout in its chain into io.Discard.
+func (p *Page[T]) dryRun(data T) error {
+	set, err := p.Layout.Loader.loadPage(p.Layout.Name, p.Template, false, nil)
+	if err != nil {
+		return err
+	}
+	if err := set.tmpl.ExecuteTemplate(io.Discard, p.Template+".html", data); err != nil && !nilPointerErr(err) {
+		return err
+	}
+	layoutData := LayoutData{Data: data}
+	for _, name := range set.layouts {
+		if err := set.tmpl.ExecuteTemplate(io.Discard, name, layoutData); err != nil && !nilPointerErr(err) {
+			return fmt.Errorf("layout %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// allocPointers points the nil pointers in v, and in the exported fields
+// of the structs it holds, at zero values. A type already being filled is
+// left nil, so recursive types end.
+func allocPointers(v reflect.Value, filling map[reflect.Type]bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() || filling[v.Type()] {
+			return
+		}
+		filling[v.Type()] = true
+		p := reflect.New(v.Type().Elem())
+		allocPointers(p.Elem(), filling)
+		v.Set(p)
+		delete(filling, v.Type())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				allocPointers(f, filling)
+			}
+		}
+	}
+}
+
+// nilPointerErr reports whether err is execution stopping at a nil
+// pointer, e.g. a nil map value or a pointer the fixture left unset.
+func nilPointerErr(err error) bool {
+	return strings.Contains(err.Error(), "nil pointer evaluating")
+}
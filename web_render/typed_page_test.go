@@ -0,0 +1,45 @@
+package web_render
+
+import (
+	"strings"
+	"testing"
+)
+
+type typedUser struct {
+	Name    string
+	Manager *typedUser
+}
+
+type typedProfile struct {
+	User  *typedUser
+	Teams map[string]*typedUser
+}
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		wantErr string
+	}{
+		{name: "nested pointer", page: `<p>{{.User.Name}}</p>`},
+		{name: "recursive pointer", page: `<p>{{.User.Manager.Manager.Name}}</p>`},
+		{name: "nil map value", page: `<p>{{(index .Teams "core").Name}}</p>`},
+		{name: "missing field", page: `<p>{{.User.Email}}</p>`, wantErr: "can't evaluate field Email"},
+		{name: "missing top-level field", page: `<p>{{.Owner}}</p>`, wantErr: "can't evaluate field Owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := testLayout(map[string]string{"profile": tt.page})
+			_, err := NewPage[*typedProfile](layout, "profile")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewPage: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewPage error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}